@@ -0,0 +1,319 @@
+// Command build is the build step of the buildpack. It reads the function
+// that detect put in the build plan and generates the main package that
+// calls it into a layer, which can then be built with:
+//
+//	cd <LAYERS_DIR>/scaffolding && go build -o app .
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/mod/modfile"
+)
+
+// templates are the main packages for the signatures, keyed by the id of
+// the signature. For example http.go.tmpl is used for the "http" signature.
+//
+//go:embed templates/*.go.tmpl
+var templates embed.FS
+
+// layerName is the name of the layer the scaffolding is written to.
+const layerName = "scaffolding"
+
+// defaultGoVersion is used if the application doesn't declare one.
+const defaultGoVersion = "1.22"
+
+// Plan is the buildpack plan that detect wrote, see cmd/detect.
+type Plan struct {
+	Entries []struct {
+		Name     string   `toml:"name"`
+		Metadata Metadata `toml:"metadata"`
+	} `toml:"entries"`
+}
+
+// Metadata of a plan entry.
+type Metadata struct {
+	// Package is the import path of the package with the function.
+	Package string `toml:"package"`
+	// Function is the name of the function.
+	Function string `toml:"function"`
+	// Kind is the kind of function. Functions, variables and factories can
+	// be used by the built in templates, types need a template.
+	Kind string `toml:"kind"`
+	// Receiver is the type of a method, which is called on an instance
+	// created by the Constructor. ConstructorError is set if that also
+	// returns an error.
+	Receiver         string `toml:"receiver"`
+	PointerReceiver  bool   `toml:"pointerReceiver"`
+	Constructor      string `toml:"constructor"`
+	ConstructorError bool   `toml:"constructorError"`
+	// FactoryError is set if a factory also returns an error.
+	FactoryError bool `toml:"factoryError"`
+	// Params are the arguments of the function.
+	Params []Param `toml:"params"`
+	// Signature is the id of the signature that matched, which selects the
+	// template.
+	Signature string `toml:"signature"`
+	// Template optionally is the file, relative to the application, with
+	// the template to use instead of the one for the signature.
+	Template string `toml:"template"`
+}
+
+// Param is an argument of the function, see detect.FunctionArg. Only the
+// parts the templates need are read.
+type Param struct {
+	ImportPath string `toml:"importPath"`
+	Name       string `toml:"name"`
+	Pointer    bool   `toml:"pointer"`
+	Channel    string `toml:"channel"`
+}
+
+// Setup returns the statements the templates put before using the Handler,
+// which create the instance of the Receiver of a method or call a factory.
+func (m *Metadata) Setup() string {
+	var name, call, what string
+	var withErr bool
+	switch {
+	case m.Receiver != "":
+		name, call, what, withErr = "instance", m.Constructor, m.Receiver, m.ConstructorError
+	case m.Kind == "factory":
+		name, call, what, withErr = "handler", m.Function, "the function", m.FactoryError
+	default:
+		return ""
+	}
+	if !withErr {
+		return fmt.Sprintf("%s := fn.%s()", name, call)
+	}
+	return fmt.Sprintf("%s, err := fn.%s()\nif err != nil {\nlog.Fatalf(\"Failed to create %s: %%s\", err)\n}", name, call, what)
+}
+
+// Handler returns the expression for the function, after the Setup.
+func (m *Metadata) Handler() string {
+	switch {
+	case m.Receiver != "":
+		return "instance." + m.Function
+	case m.Kind == "factory":
+		return "handler"
+	}
+	return "fn." + m.Function
+}
+
+func main() {
+	layersDir, planFileName, err := buildArgs(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Usage: %s <LAYERS_DIR> <PLATFORM_DIR> <BUILD_PLAN> : %s", os.Args[0], err)
+	}
+
+	var plan Plan
+	if _, err := toml.DecodeFile(planFileName, &plan); err != nil {
+		log.Fatalf("Failed to read the build plan %q : %s", planFileName, err)
+	}
+	var meta *Metadata
+	for i := range plan.Entries {
+		if plan.Entries[i].Metadata.Function != "" {
+			meta = &plan.Entries[i].Metadata
+			break
+		}
+	}
+	if meta == nil {
+		log.Fatalf("No function in the build plan %q", planFileName)
+	}
+
+	// Detect runs in the application directory, and so does build.
+	appDir, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get the application directory: %s", err)
+	}
+	if err := writeLayer(layersDir, appDir, meta); err != nil {
+		log.Fatalf("Failed to write the scaffolding: %s", err)
+	}
+	log.Printf("Wrote the scaffolding for %s.%s to %s", meta.Package, meta.Function, filepath.Join(layersDir, layerName))
+}
+
+// buildArgs returns the layers directory and the buildpack plan path. Since
+// buildpack API 0.8 they are passed as CNB_LAYERS_DIR and CNB_BP_PLAN_PATH,
+// before that as the first and the third argument.
+func buildArgs(args []string) (string, string, error) {
+	layersDir, planFileName := os.Getenv("CNB_LAYERS_DIR"), os.Getenv("CNB_BP_PLAN_PATH")
+	if len(args) >= 3 {
+		if layersDir == "" {
+			layersDir = args[0]
+		}
+		if planFileName == "" {
+			planFileName = args[2]
+		}
+	}
+	if layersDir == "" || planFileName == "" {
+		return "", "", errors.New("the layers directory and the buildpack plan path are required")
+	}
+	return layersDir, planFileName, nil
+}
+
+// writeLayer writes the main package that calls the function described by
+// meta, as a module in a workspace together with the application in appDir,
+// so that it can be built without touching the application.
+func writeLayer(layersDir, appDir string, meta *Metadata) error {
+	// A template from the application may know what to do with the others.
+	if meta.Template == "" {
+		switch meta.Kind {
+		case "", "function", "variable", "factory":
+		default:
+			return fmt.Errorf("%s %s can not be called directly, use the template metadata to provide a template", meta.Kind, meta.Function)
+		}
+		if meta.Receiver != "" && meta.Constructor == "" {
+			return fmt.Errorf("method %s needs an instance of %s, add a constructor like func New%s() *%s or use the template metadata to provide a template", meta.Function, meta.Receiver, meta.Receiver, meta.Receiver)
+		}
+		if check := paramChecks[meta.Signature]; check != nil {
+			if err := check(meta.Params); err != nil {
+				return fmt.Errorf("%s %s can not be used with the %s template: %w", meta.Kind, meta.Function, meta.Signature, err)
+			}
+		}
+	}
+	src, err := readTemplate(appDir, meta)
+	if err != nil {
+		return err
+	}
+	t, err := template.New("main").Parse(src)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, meta); err != nil {
+		return err
+	}
+	mainGo, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code is not valid: %w", err)
+	}
+
+	goVersion, modules, err := readModules(appDir)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(layersDir, layerName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	goWork := fmt.Sprintf("go %s\n\nuse (\n\t.\n", goVersion)
+	for _, m := range modules {
+		goWork += fmt.Sprintf("\t%s\n", m)
+	}
+	goWork += ")\n"
+	files := map[string]string{
+		"main.go": string(mainGo),
+		"go.mod":  fmt.Sprintf("module %s\n\ngo %s\n", layerName, goVersion),
+		"go.work": goWork,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	// The scaffolding is only needed to build the application.
+	return os.WriteFile(filepath.Join(layersDir, layerName+".toml"), []byte("[types]\nbuild = true\n"), 0644)
+}
+
+// paramChecks check that the built in templates using the params of the
+// function can use them, keyed by the id of the signature.
+var paramChecks = map[string]func([]Param) error{
+	"grpc-stream": checkStreamParams,
+}
+
+// checkStreamParams checks that the function takes a context, a channel to
+// receive the requests from and one to send the responses to, both of
+// pointers to types the scaffolding can import.
+func checkStreamParams(params []Param) error {
+	if len(params) != 3 {
+		return fmt.Errorf("has %d args, expected a context.Context, a channel of requests and a channel of responses", len(params))
+	}
+	if params[0].ImportPath != "context" || params[0].Name != "Context" {
+		return errors.New("arg 1 is not a context.Context")
+	}
+	if params[1].Channel != "RECEIVE" && params[1].Channel != "BOTH" {
+		return errors.New("arg 2 is not a channel the requests can be received from")
+	}
+	if params[2].Channel != "SEND" && params[2].Channel != "BOTH" {
+		return errors.New("arg 3 is not a channel the responses can be sent to")
+	}
+	for i, p := range params[1:] {
+		if !p.Pointer {
+			return fmt.Errorf("arg %d is not a channel of pointers", i+2)
+		}
+		if p.ImportPath == "" {
+			return fmt.Errorf("arg %d is a channel of %s, which has to be declared in another package to be imported", i+2, p.Name)
+		}
+	}
+	return nil
+}
+
+// readTemplate returns the template from the plan, or the one for the
+// signature.
+func readTemplate(appDir string, meta *Metadata) (string, error) {
+	if meta.Template != "" {
+		src, err := os.ReadFile(filepath.Join(appDir, meta.Template))
+		return string(src), err
+	}
+	src, err := templates.ReadFile("templates/" + meta.Signature + ".go.tmpl")
+	if err != nil {
+		return "", fmt.Errorf("no template for signature %q, use the template metadata to provide one", meta.Signature)
+	}
+	return string(src), nil
+}
+
+// readModules returns the go version and the directories of the modules of
+// the application in appDir. That's the modules of its go.work if it's a
+// workspace, otherwise appDir itself.
+func readModules(appDir string) (string, []string, error) {
+	goWork := filepath.Join(appDir, "go.work")
+	data, err := os.ReadFile(goWork)
+	if errors.Is(err, fs.ErrNotExist) {
+		goVersion, err := readGoVersion(appDir)
+		return goVersion, []string{appDir}, err
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	work, err := modfile.ParseWork(goWork, data, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	var modules []string
+	for _, use := range work.Use {
+		if filepath.IsAbs(use.Path) {
+			modules = append(modules, use.Path)
+		} else {
+			modules = append(modules, filepath.Join(appDir, use.Path))
+		}
+	}
+	goVersion := defaultGoVersion
+	if work.Go != nil {
+		goVersion = work.Go.Version
+	}
+	return goVersion, modules, nil
+}
+
+// readGoVersion returns the go version of the module in appDir.
+func readGoVersion(appDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(appDir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return "", err
+	}
+	if f.Go == nil {
+		return defaultGoVersion, nil
+	}
+	return f.Go.Version, nil
+}
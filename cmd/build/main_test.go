@@ -0,0 +1,339 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// writeApp writes the files of an application to a new directory.
+func writeApp(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readLayerFile(t *testing.T, layersDir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(layersDir, layerName, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteLayerTemplates(t *testing.T) {
+	streamParams := []Param{
+		{ImportPath: "context", Name: "Context"},
+		{ImportPath: "example.com/app/proto", Name: "Request", Pointer: true, Channel: "RECEIVE"},
+		{ImportPath: "example.com/app/proto", Name: "Response", Pointer: true, Channel: "BOTH"},
+	}
+	tests := []struct {
+		name string
+		meta Metadata
+		want []string
+	}{{
+		name: "http function",
+		meta: Metadata{Package: "example.com/app", Function: "Handle", Kind: "function", Signature: "http"},
+		want: []string{`fn "example.com/app"`, `http.HandleFunc("/", fn.Handle)`},
+	}, {
+		name: "http method",
+		meta: Metadata{Package: "example.com/app", Function: "Handle", Kind: "function", Signature: "http",
+			Receiver: "Server", PointerReceiver: true, Constructor: "NewServer", ConstructorError: true},
+		want: []string{"instance, err := fn.NewServer()", "Failed to create Server", `http.HandleFunc("/", instance.Handle)`},
+	}, {
+		name: "cloudevents factory",
+		meta: Metadata{Package: "example.com/app", Function: "NewReceiver", Kind: "factory", Signature: "cloudevents"},
+		want: []string{"handler := fn.NewReceiver()", "c.StartReceiver(context.Background(), handler)"},
+	}, {
+		name: "grpc stream variable",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "variable", Signature: "grpc-stream", Params: streamParams},
+		want: []string{
+			`req "example.com/app/proto"`,
+			"make(chan *resp.Response)",
+			"new(req.Request)",
+			"done <- fn.Stream(ctx, requests, responses)",
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appDir := writeApp(t, map[string]string{"go.mod": "module example.com/app\n\ngo 1.21\n"})
+			layersDir := t.TempDir()
+			if err := writeLayer(layersDir, appDir, &test.meta); err != nil {
+				t.Fatalf("Failed to write the layer: %s", err)
+			}
+			mainGo := readLayerFile(t, layersDir, "main.go")
+			for _, want := range test.want {
+				if !strings.Contains(mainGo, want) {
+					t.Errorf("Wanted main.go to contain %q:\n%s", want, mainGo)
+				}
+			}
+			if goMod := readLayerFile(t, layersDir, "go.mod"); goMod != "module scaffolding\n\ngo 1.21\n" {
+				t.Errorf("Unexpected go.mod:\n%s", goMod)
+			}
+			if goWork := readLayerFile(t, layersDir, "go.work"); !strings.Contains(goWork, "\t"+appDir+"\n") {
+				t.Errorf("Wanted go.work to use %s:\n%s", appDir, goWork)
+			}
+		})
+	}
+}
+
+func TestWriteLayerErrors(t *testing.T) {
+	ctx := Param{ImportPath: "context", Name: "Context"}
+	request := Param{ImportPath: "example.com/app/proto", Name: "Request", Pointer: true, Channel: "RECEIVE"}
+	response := Param{ImportPath: "example.com/app/proto", Name: "Response", Pointer: true, Channel: "SEND"}
+	tests := []struct {
+		name string
+		meta Metadata
+		err  string
+	}{{
+		name: "type",
+		meta: Metadata{Package: "example.com/app", Function: "Handler", Kind: "type", Signature: "http"},
+		err:  "type Handler can not be called directly",
+	}, {
+		name: "method without constructor",
+		meta: Metadata{Package: "example.com/app", Function: "Handle", Kind: "function", Signature: "http", Receiver: "Server"},
+		err:  "method Handle needs an instance of Server",
+	}, {
+		name: "unknown signature",
+		meta: Metadata{Package: "example.com/app", Function: "Handle", Kind: "function", Signature: "smtp"},
+		err:  `no template for signature "smtp"`,
+	}, {
+		name: "stream without params",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream"},
+		err:  "function Stream can not be used with the grpc-stream template: has 0 args, expected a context.Context, a channel of requests and a channel of responses",
+	}, {
+		name: "stream without context",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream",
+			Params: []Param{{Name: "string"}, request, response}},
+		err: "arg 1 is not a context.Context",
+	}, {
+		name: "stream sending requests",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream",
+			Params: []Param{ctx, response, response}},
+		err: "arg 2 is not a channel the requests can be received from",
+	}, {
+		name: "stream receiving responses",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream",
+			Params: []Param{ctx, request, request}},
+		err: "arg 3 is not a channel the responses can be sent to",
+	}, {
+		name: "stream of values",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream",
+			Params: []Param{ctx, {ImportPath: "example.com/app/proto", Name: "Request", Channel: "RECEIVE"}, response}},
+		err: "arg 2 is not a channel of pointers",
+	}, {
+		name: "stream of local types",
+		meta: Metadata{Package: "example.com/app", Function: "Stream", Kind: "function", Signature: "grpc-stream",
+			Params: []Param{ctx, request, {Name: "Response", Pointer: true, Channel: "SEND"}}},
+		err: "arg 3 is a channel of Response, which has to be declared in another package to be imported",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appDir := writeApp(t, map[string]string{"go.mod": "module example.com/app\n"})
+			err := writeLayer(t.TempDir(), appDir, &test.meta)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Wanted error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWriteLayerAppTemplate(t *testing.T) {
+	appDir := writeApp(t, map[string]string{
+		"go.mod": "module example.com/app\n",
+		"main.go.tmpl": `package main
+
+import fn "{{ .Package }}"
+
+func main() { fn.{{ .Function }}.Serve() }
+`,
+	})
+	layersDir := t.TempDir()
+	meta := &Metadata{Package: "example.com/app", Function: "Handler", Kind: "type", Template: "main.go.tmpl"}
+	if err := writeLayer(layersDir, appDir, meta); err != nil {
+		t.Fatalf("Failed to write the layer: %s", err)
+	}
+	if mainGo := readLayerFile(t, layersDir, "main.go"); !strings.Contains(mainGo, "fn.Handler.Serve()") {
+		t.Errorf("Wanted the template of the application, got:\n%s", mainGo)
+	}
+	// No go directive, so the default version.
+	if goMod := readLayerFile(t, layersDir, "go.mod"); !strings.Contains(goMod, "go "+defaultGoVersion+"\n") {
+		t.Errorf("Wanted go %s, got:\n%s", defaultGoVersion, goMod)
+	}
+}
+
+// TestWriteLayerBuilds builds the scaffolding of a method with the go tool.
+func TestWriteLayerBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Builds with the go tool")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("No go tool")
+	}
+	appDir := writeApp(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"server.go": `package app
+
+import "net/http"
+
+type Server struct{ greeting string }
+
+func NewServer() (*Server, error) { return &Server{greeting: "hello"}, nil }
+
+func (s *Server) Handle(w http.ResponseWriter, r *http.Request) { w.Write([]byte(s.greeting)) }
+`,
+	})
+	layersDir := t.TempDir()
+	meta := &Metadata{Package: "example.com/app", Function: "Handle", Kind: "function", Signature: "http",
+		Receiver: "Server", PointerReceiver: true, Constructor: "NewServer", ConstructorError: true}
+	if err := writeLayer(layersDir, appDir, meta); err != nil {
+		t.Fatalf("Failed to write the layer: %s", err)
+	}
+	cmd := exec.Command(goTool, "build", "-o", os.DevNull, ".")
+	cmd.Dir = filepath.Join(layersDir, layerName)
+	// Workspaces only allow some -mod flags.
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build the scaffolding: %s\n%s\n%s", err, out, readLayerFile(t, layersDir, "main.go"))
+	}
+}
+
+func TestPlanMetadata(t *testing.T) {
+	// The metadata as cmd/detect writes it, with all the fields of the
+	// params.
+	const planTOML = `[[entries]]
+name = "grpc-go-function"
+
+[entries.metadata]
+package = "example.com/app"
+function = "Receive"
+kind = "function"
+signature = "grpc-stream"
+receiver = "Server"
+pointerReceiver = true
+constructor = "NewServer"
+constructorError = false
+
+[[entries.metadata.params]]
+importPath = "context"
+name = "Context"
+paramName = "ctx"
+
+[[entries.metadata.params]]
+importPath = "example.com/app/proto"
+name = "Request"
+pointer = true
+channel = "RECEIVE"
+`
+	var plan Plan
+	if _, err := toml.Decode(planTOML, &plan); err != nil {
+		t.Fatalf("Failed to decode the plan: %s", err)
+	}
+	meta := plan.Entries[0].Metadata
+	if meta.Receiver != "Server" || !meta.PointerReceiver || meta.Constructor != "NewServer" || len(meta.Params) != 2 {
+		t.Fatalf("Unexpected metadata %+v", meta)
+	}
+	if want := (Param{ImportPath: "example.com/app/proto", Name: "Request", Pointer: true, Channel: "RECEIVE"}); meta.Params[1] != want {
+		t.Errorf("Wanted %+v, got %+v", want, meta.Params[1])
+	}
+	if setup, handler := meta.Setup(), meta.Handler(); setup != "instance := fn.NewServer()" || handler != "instance.Receive" {
+		t.Errorf("Unexpected setup %q and handler %q", setup, handler)
+	}
+}
+
+func TestReadModules(t *testing.T) {
+	appDir := writeApp(t, map[string]string{
+		"go.work":          "go 1.22\n\nuse (\n\t./api\n\t/abs/lib\n)\n",
+		"api/go.mod":       "module example.com/api\n\ngo 1.21\n",
+		"single/go.mod":    "module example.com/single\n\ngo 1.20\n",
+		"noversion/go.mod": "module example.com/noversion\n",
+	})
+	goVersion, modules, err := readModules(appDir)
+	if err != nil {
+		t.Fatalf("Failed to read the workspace: %s", err)
+	}
+	if want := []string{filepath.Join(appDir, "api"), "/abs/lib"}; goVersion != "1.22" || strings.Join(modules, ",") != strings.Join(want, ",") {
+		t.Errorf("Wanted go 1.22 and %v, got go %s and %v", want, goVersion, modules)
+	}
+
+	single := filepath.Join(appDir, "single")
+	goVersion, modules, err = readModules(single)
+	if err != nil {
+		t.Fatalf("Failed to read the module: %s", err)
+	}
+	if goVersion != "1.20" || len(modules) != 1 || modules[0] != single {
+		t.Errorf("Wanted go 1.20 and %s, got go %s and %v", single, goVersion, modules)
+	}
+
+	if goVersion, _, err := readModules(filepath.Join(appDir, "noversion")); err != nil || goVersion != defaultGoVersion {
+		t.Errorf("Wanted the default go version, got %s, %v", goVersion, err)
+	}
+	if _, _, err := readModules(t.TempDir()); err == nil {
+		t.Error("Wanted an error without a go.mod")
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		layers    string
+		plan      string
+		wantError bool
+	}{
+		{name: "arguments", args: []string{"/layers", "/platform", "/plan.toml"}, layers: "/layers", plan: "/plan.toml"},
+		{
+			name:   "environment",
+			env:    map[string]string{"CNB_LAYERS_DIR": "/env-layers", "CNB_BP_PLAN_PATH": "/env-plan.toml"},
+			args:   []string{"/layers", "/platform", "/plan.toml"},
+			layers: "/env-layers",
+			plan:   "/env-plan.toml",
+		},
+		{
+			name:   "environment only",
+			env:    map[string]string{"CNB_LAYERS_DIR": "/env-layers", "CNB_BP_PLAN_PATH": "/env-plan.toml"},
+			layers: "/env-layers",
+			plan:   "/env-plan.toml",
+		},
+		{
+			name:   "mixed",
+			env:    map[string]string{"CNB_BP_PLAN_PATH": "/env-plan.toml"},
+			args:   []string{"/layers", "/platform", "/plan.toml"},
+			layers: "/layers",
+			plan:   "/env-plan.toml",
+		},
+		{name: "missing", args: []string{"/layers", "/platform"}, wantError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("CNB_LAYERS_DIR", test.env["CNB_LAYERS_DIR"])
+			t.Setenv("CNB_BP_PLAN_PATH", test.env["CNB_BP_PLAN_PATH"])
+			layers, plan, err := buildArgs(test.args)
+			if test.wantError {
+				if err == nil {
+					t.Errorf("Wanted an error, got %q and %q", layers, plan)
+				}
+				return
+			}
+			if err != nil || layers != test.layers || plan != test.plan {
+				t.Errorf("Wanted %q and %q, got %q and %q, %v", test.layers, test.plan, layers, plan, err)
+			}
+		})
+	}
+}
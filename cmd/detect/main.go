@@ -1,16 +1,17 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"go/build"
 	"github.com/kelseyhightower/envconfig"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
-	"text/template"
 
 	"github.com/vaikas/gofunctypechecker/pkg/detect"
 )
@@ -19,202 +20,348 @@ const supportedFuncs = `
 Could not find a supported function signature. Supported function signatures
 are listed below. Note that the function must be visible outside of the package
 (capitalized, for example, Handler vs. handler).
-Supported function Signatures:
+Supported function Signatures (from %s):
 %s
 `
 
+// PlanArguments are what the plan template is executed with.
 type PlanArguments struct {
-	Name string
-	Package string
+	// Name is the name of the plan, see planMetadata.
+	Name     string
+	Package  string
 	Function string
-	Env map[string]string
+	// Provides and Requires are the names the plan provides and requires,
+	// see providesMetadata and requiresMetadata.
+	Provides []string
+	Requires []string
+	// ID and Metadata are the ones of the signature that matched.
+	ID       string
+	Metadata map[string]string
+	// Kind is the kind of declaration that was found, for example
+	// "function", "variable", "factory" or "type". See detect.Kind.
+	Kind string
+	// Receiver, PointerReceiver, Constructor and ConstructorError describe
+	// the type of a method, and how to create an instance of it. See
+	// detect.FunctionDetails.
+	Receiver         string
+	PointerReceiver  bool
+	Constructor      string
+	ConstructorError bool
+	// FactoryError is set if a factory also returns an error.
+	FactoryError bool
+	// Params are the arguments of the function.
+	Params []detect.FunctionArg
+	Env    map[string]string
 }
 
 const defaultPlanName = "http-go-function"
 
-const defaultPlanTemplate = `
-[[provides]]
-name = "{{ .Name }}"
-[[requires]]
-name = "{{ .Name }}"
-[requires.metadata]
-package = "{{ .Package }}"
-function = "{{ .Function }}"
-exampleEnv = "{{ index .Env "PWD" }}"
-`
+// Exit codes of detect, see
+// https://github.com/buildpacks/spec/blob/main/buildpack.md#detection
+const (
+	// detectPass means that the buildpack applies and wrote its plan.
+	detectPass = 0
+	// detectFail means that the buildpack doesn't apply, for example
+	// because there is no matching function.
+	detectFail = 100
+	// detectError is for everything that went wrong, which fails the
+	// whole build.
+	detectError = 1
+)
 
-// defaultSignatures that just use HTTP handler.
-var defaultSignatures = []detect.FunctionSignature{
-	{In: []detect.FunctionArg{
-		{ImportPath: "net/http", Name: "ResponseWriter"},
-		{ImportPath: "net/http", Name: "Request", Pointer: true},
-	}}}
+// Metadata keys of the signatures that control the build plan written for a
+// function matching them.
+const (
+	// planMetadata is the name of the plan, which is also what's provided
+	// and required unless providesMetadata or requiresMetadata are given.
+	planMetadata = "plan"
+	// planTemplateMetadata is the file or URL of the plan template to use
+	// instead of PLAN_TEMPLATE.
+	planTemplateMetadata = "planTemplate"
+	// providesMetadata and requiresMetadata are comma separated lists of
+	// names.
+	providesMetadata = "provides"
+	requiresMetadata = "requires"
+	// protocolMetadata is the protocol of the signature. If PROTOCOL is set
+	// only the signatures without a protocol or with the same one match.
+	protocolMetadata = "protocol"
+)
 
 type EnvConfig struct {
-	GoPackage  string `envconfig:"GO_PACKAGE" default:"./"`
-	GoFunction string `envconfig:"GO_FUNCTION" default:"Receiver"`
-	Protocol   string `envconfig:"PROTOCOL" default:"http"`
-	Signatures string `envconfig:"SIGNATURES"`
+	GoPackage    string `envconfig:"GO_PACKAGE" default:"./"`
+	GoFunction   string `envconfig:"GO_FUNCTION"`
+	Protocol     string `envconfig:"PROTOCOL"`
+	Signatures   string `envconfig:"SIGNATURES"`
 	PlanTemplate string `envconfig:"PLAN_TEMPLATE"`
+	// SignaturesFormat is the format of the SIGNATURES, one of json, yaml
+	// or toml. By default it's inferred.
+	SignaturesFormat string `envconfig:"SIGNATURES_FORMAT"`
+	// TypeCheck resolves the types with go/types, which is needed for
+	// example for variables of named func types like http.HandlerFunc.
+	TypeCheck bool `envconfig:"TYPE_CHECK"`
+	// Factories also matches functions returning a matching function.
+	Factories bool `envconfig:"FACTORIES"`
+	// SelectionPolicy is one of detect.SelectionPolicy. By default it's
+	// exact if GO_FUNCTION is set (to something other than "") and unique
+	// otherwise.
+	SelectionPolicy string `envconfig:"SELECTION_POLICY"`
 }
 
-func printSupportedFunctionsAndExit(sigs string) {
-	fmt.Println(supportedFuncs)
-	os.Exit(100)
+func printSupportedFunctions(detector *detect.Detector) {
+	fmt.Printf(supportedFuncs, detector.Source(), detector.Signatures())
+}
+
+// printDiagnostics prints why the functions that came close to one of the
+// signatures didn't match.
+func printDiagnostics(diags []detect.Diagnostic) {
+	if len(diags) == 0 {
+		return
+	}
+	fmt.Println("Functions that almost match a supported function signature:")
+	for i := range diags {
+		fmt.Println(diags[i].String())
+	}
 }
 
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
 	log.Println("ARGS: ", os.Args)
+	platformDir, planFileName, err := detectArgs(os.Args[1:])
+	if err != nil {
+		log.Printf("Usage: %s <PLATFORM_DIR> <BUILD_PLAN> : %s", os.Args[0], err)
+		return detectError
+	}
+	log.Println("using plan file: ", planFileName)
+	if err := loadPlatformEnv(platformDir); err != nil {
+		log.Printf("Failed to read the platform env from %q : %s", platformDir, err)
+		return detectError
+	}
 	for _, e := range os.Environ() {
 		log.Println(e)
 	}
 
-	if len(os.Args) < 3 {
-		log.Println("Usage: %s <PLATFORM_DIR> <BUILD_PLAN>", os.Args[0])
-		os.Exit(100)
-	}
-
-	planFileName := os.Args[2]
-	log.Println("using plan file: ", planFileName)
-
 	// Grab the env variables
 	var envConfig EnvConfig
 	if err := envconfig.Process("detect", &envConfig); err != nil {
-		log.Printf("Failed to process env variables: %s\n", err)
+		log.Printf("Failed to process env variables: %s", err)
+		return detectError
 	}
 
-	moduleName, err := readModuleName()
-	if err != nil {
-		log.Println("Failed to read go.mod file: ", err)
-		os.Exit(100)
-	}
-	// There are two ENV variables that control what should be checked.
-	// We yank the base package from go.mod and append CE_GO_PACKAGE into it
-	// if it's given.
-	// TODO: Use library for these...
+	// GO_PACKAGE is the directory of the package to check, relative to the application. The
+	// import path that we'll use with the scaffolding comes from the module it's part of,
+	// which is not necessarily the one at the root of the application, for example in a
+	// workspace. Looks for example like: github.com/vaikas/buildpackstuffhttp/pkg/detect/testdata
 	goPackage := envConfig.GoPackage
 	if !strings.HasSuffix(goPackage, "/") {
 		goPackage = goPackage + "/"
 	}
-	// fullGoPackage is the import path that we'll use with the scaffolding.
-	// Looks for example like: github.com/vaikas/buildpackstuffhttp/pkg/detect/testdata
-	fullGoPackage := moduleName
-	if goPackage != "./" {
-		fullGoPackage = fullGoPackage + "/" + filepath.Clean(goPackage)
+	module, err := detect.FindModule(goPackage)
+	if errors.Is(err, detect.ErrNoModule) {
+		// Without a go.mod this is not an application we can build.
+		log.Println("Failed to find go.mod file: ", err)
+		return detectFail
+	}
+	if err != nil {
+		log.Printf("Failed to read the module of %s : %s", goPackage, err)
+		return detectError
+	}
+	fullGoPackage, err := module.ImportPath(goPackage)
+	if err != nil {
+		log.Printf("Failed to get the import path of %s : %s", goPackage, err)
+		return detectError
 	}
 	log.Println("Using relative path to look for function: ", goPackage)
 
 	goFunction := envConfig.GoFunction
+	policy := detect.SelectExact
+	if goFunction == "" {
+		policy = detect.SelectUnique
+	}
+	if envConfig.SelectionPolicy != "" {
+		if policy, err = detect.ParseSelectionPolicy(envConfig.SelectionPolicy); err != nil {
+			log.Printf("Invalid SELECTION_POLICY : %s", err)
+			return detectError
+		}
+	}
 
 	// Construct the detector. Either using default, fetch the config from a URL or from a file.
+	// The warnings about functions that can't be checked go to the same log as ours. The
+	// scaffolding is a different package, so it can only call exported functions.
+	opts := []detect.Option{detect.WithLogger(slog.Default()), detect.WithExportedOnly()}
+	if envConfig.TypeCheck {
+		opts = append(opts, detect.WithTypeChecking())
+	}
+	if envConfig.Factories {
+		opts = append(opts, detect.WithFactories())
+	}
 	var detector *detect.Detector
 	if envConfig.Signatures == "" {
 		// Just use defaults
-		detector = detect.NewDetector(defaultSignatures)
-	} else if (strings.HasSuffix(envConfig.Signatures, "http://") || strings.HasSuffix(envConfig.Signatures, "https://")) {
-		detector, err = detect.NewDetectorFromURL(envConfig.Signatures)
-		if err != nil {
-			log.Fatalf("Failed to create detector with signatures from URL %q : %s\n", envConfig.Signatures, err)
-			os.Exit(100)
-		}
+		detector = detect.NewDetector(detect.DefaultSignatures, opts...)
 	} else {
-		detector, err = detect.NewDetectorFromFile(envConfig.Signatures)
-		if err != nil {
-			log.Fatalf("Failed to create detector with signatures from file %q : %s\n", envConfig.Signatures, err)
-			os.Exit(100)
-		}
-	}
-
-	// Grab the plan template if specified, or use the default.
-	planTemplate := defaultPlanTemplate
-	if envConfig.PlanTemplate != "" {
-		if (strings.HasSuffix(envConfig.PlanTemplate, "http://") || strings.HasSuffix(envConfig.PlanTemplate, "https://")) {
-			resp, err := http.Get(envConfig.PlanTemplate)
-			if err != nil {
-				log.Fatalf("Failed to read plan template from URL %q : %s\n", envConfig.PlanTemplate, err)
-				os.Exit(100)
-			}
-			defer resp.Body.Close()
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				log.Fatalf("Failed to read plan template from URL %q : %s\n", envConfig.PlanTemplate, err)
-				os.Exit(100)
+		var format detect.Format
+		if envConfig.SignaturesFormat != "" {
+			if format, err = detect.ParseFormat(envConfig.SignaturesFormat); err != nil {
+				log.Printf("Invalid SIGNATURES_FORMAT : %s", err)
+				return detectError
 			}
-			planTemplate = string(body)
+		}
+		var config *detect.Config
+		if isURL(envConfig.Signatures) {
+			config, err = detect.LoadConfigURL(envConfig.Signatures, format)
 		} else {
-			body, err := ioutil.ReadFile(envConfig.PlanTemplate)
-			if err != nil {
-				log.Fatalf("Failed to read plan template from file %q : %s\n", envConfig.PlanTemplate, err)
-				os.Exit(100)
-			}
-			planTemplate = string(body)
+			config, err = detect.LoadConfigFile(envConfig.Signatures, format)
+		}
+		if err != nil {
+			log.Printf("Failed to read the signatures from %q : %s", envConfig.Signatures, err)
+			return detectError
 		}
+		detector = detect.NewDetectorFromConfig(config, opts...)
 	}
 
-	// read all go files from the directory that was given. Note that if no directory (GO_PACKAGE)
-	// was given, this is ./
-	files, err := filepath.Glob(fmt.Sprintf("%s*.go", goPackage))
+	// Scan all the go files of the package in the directory that was given. Note that if no
+	// directory (GO_PACKAGE) was given, this is ./
+	log.Printf("Processing package %s", goPackage)
+	pkg, err := detector.ScanPackage(goPackage)
+	var noGo *build.NoGoError
+	if errors.As(err, &noGo) {
+		// Without Go files there's no function, so the buildpack doesn't apply.
+		log.Printf("No Go files in package %s : %s", goPackage, err)
+		printSupportedFunctions(detector)
+		return detectFail
+	}
 	if err != nil {
-		log.Printf("failed to read directory %s : %s\n", goPackage, err)
-		printSupportedFunctionsAndExit(detector.Signatures())
+		// Files that can't be parsed or read have to be fixed rather than skipped.
+		log.Printf("failed to read package %s : %s", goPackage, err)
+		return detectError
 	}
 
-	for _, f := range files {
-		log.Printf("Processing file %s\n", f)
-		// read file
-		file, err := os.Open(f)
-		if err != nil {
-			log.Println(err)
-			os.Exit(100)
-		}
-		defer file.Close()
+	// If a protocol was given, only the signatures for it count.
+	if envConfig.Protocol != "" {
+		pkg.Matches = filterByProtocol(pkg.Matches, envConfig.Protocol)
+	}
 
-		// read the whole file in
-		srcbuf, err := ioutil.ReadAll(file)
-		if err != nil {
-			log.Println(err)
-			printSupportedFunctionsAndExit(detector.Signatures())
+	// Pick the function according to the policy: the one the user specified, the only one in
+	// the whole package or the one matching the signature with the highest priority.
+	log.Printf("Selecting the function with policy %s", policy)
+	deets, err := pkg.Select(policy, goFunction)
+	if err != nil {
+		log.Printf("Failed to select a function in package %q : %s", goPackage, err)
+		var ambiguous *detect.AmbiguityError
+		if errors.As(err, &ambiguous) {
+			fmt.Println("Found more than one supported function, set GO_FUNCTION or SELECTION_POLICY to pick one:")
+			for i := range ambiguous.Matches {
+				m := &ambiguous.Matches[i]
+				fmt.Printf("%s: %s %s matches %s\n", m.Position, m.Kind, m.Name, m.Signature)
+			}
+			return detectFail
 		}
-		f := &detect.Function{File: f, Source: string(srcbuf)}
-		deets, err := detector.CheckFile(f)
-		if err != nil {
-			log.Panic("Failed to process file %q : %s", f, err)
+		var noMatch *detect.NoMatchError
+		if !errors.As(err, &noMatch) {
+			return detectError
 		}
-		if deets != nil {
-			log.Printf("Found supported function %q in package %q signature %q", deets.Name, deets.Package, deets.Signature)
-			// If the user didn't specify a specific function, use it. If they specified the function, make sure it
-			// matches what we found.
-			if goFunction == "" || goFunction == deets.Name {
-				deets.Package = fullGoPackage
-				if err := writePlan(planFileName, planTemplate, deets); err != nil {
-					log.Println("failed to write the build plan: ", err)
-					os.Exit(100)
-				}
-				os.Exit(0)
+		printDiagnostics(pkg.Diagnostics)
+		printSupportedFunctions(detector)
+		return detectFail
+	}
+
+	log.Printf("Found supported function %q in package %q signature %q", deets.Name, deets.Package, deets.Signature)
+	deets.Package = fullGoPackage
+	plan, err := planFor(deets, envConfig.PlanTemplate)
+	if err != nil {
+		log.Println(err)
+		return detectError
+	}
+	// Without a protocol the other matches are alternatives, in the order
+	// of their priority, in case the plan of the selected one can't be
+	// satisfied. The other policies select a single function on purpose.
+	if envConfig.Protocol == "" && policy == detect.SelectFirstByPriority {
+		for _, m := range alternatives(pkg.Matches, deets) {
+			m.Package = fullGoPackage
+			alt, err := planFor(&m, envConfig.PlanTemplate)
+			if err != nil {
+				log.Println(err)
+				return detectError
 			}
+			plan.addAlternatives(alt)
 		}
 	}
-	printSupportedFunctionsAndExit(detector.Signatures())
+	if err := plan.write(planFileName); err != nil {
+		log.Println("failed to write the build plan: ", err)
+		return detectError
+	}
+	return detectPass
 }
 
-func writePlan(planFileName, planTemplate string, details *detect.FunctionDetails) error {
-	planFile, err := os.OpenFile(planFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// planFor returns the plan for the function. The signature can name its own
+// plan template, otherwise planTemplate is used, or the default plan if that's
+// empty too.
+func planFor(details *detect.FunctionDetails, planTemplate string) (*BuildPlan, error) {
+	location := metadata(details)[planTemplateMetadata]
+	if location == "" {
+		location = planTemplate
+	}
+	args := planArguments(details)
+	if location == "" {
+		return defaultPlan(args), nil
+	}
+	src, err := readPlanTemplate(location)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read plan template %q: %w", location, err)
 	}
-	defer planFile.Close()
-
-	t, err := template.New("Plan").Parse(planTemplate)
+	plan, err := templatePlan(src, args)
 	if err != nil {
-		log.Printf("Failed to parse the template file : %s\n", err)
-		return err
+		return nil, fmt.Errorf("failed to use plan template %q: %w", location, err)
+	}
+	return plan, nil
+}
+
+// alternatives returns the matches other than selected, ordered by the
+// priority of their signatures and then by file order.
+func alternatives(matches []detect.FunctionDetails, selected *detect.FunctionDetails) []detect.FunctionDetails {
+	var ret []detect.FunctionDetails
+	for i := range matches {
+		if matches[i].Position != selected.Position {
+			ret = append(ret, matches[i])
+		}
 	}
-	args := PlanArguments{
-		Name: defaultPlanName,
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Priority() > ret[j].Priority()
+	})
+	return ret
+}
+
+// planArguments returns the arguments of the plan for the function.
+func planArguments(details *detect.FunctionDetails) *PlanArguments {
+	meta := metadata(details)
+	args := &PlanArguments{
+		Name:     defaultPlanName,
 		Function: details.Name,
-		Package: details.Package,
-		Env: make(map [string]string),
+		Package:  details.Package,
+		Kind:     string(details.Kind),
+		Metadata: meta,
+		Env:      make(map[string]string),
+
+		Receiver:         details.Receiver,
+		PointerReceiver:  details.PointerReceiver,
+		Constructor:      details.Constructor,
+		ConstructorError: details.ConstructorError,
+		FactoryError:     details.FactoryError,
+		Params:           details.Params,
+	}
+	if details.Match != nil {
+		args.ID = details.Match.ID
+	} else if details.Interface != nil {
+		args.ID = details.Interface.ID
+	}
+	if meta[planMetadata] != "" {
+		args.Name = meta[planMetadata]
 	}
+	args.Provides = names(meta[providesMetadata], args.Name)
+	args.Requires = names(meta[requiresMetadata], args.Name)
 	for _, env := range os.Environ() {
 		// env pieces are ENV=VALUE, so split them so we get a key=>value into map, which to index.
 		pieces := strings.SplitN(env, "=", 2)
@@ -222,28 +369,65 @@ func writePlan(planFileName, planTemplate string, details *detect.FunctionDetail
 			args.Env[pieces[0]] = pieces[1]
 		}
 	}
-	if err := t.Execute(planFile, args); err != nil {
-		log.Printf("Failed to execute template file: %s\n", err)
-		return err
+	return args
+}
+
+// metadata returns the metadata of the signature or interface that matched.
+func metadata(details *detect.FunctionDetails) map[string]string {
+	switch {
+	case details.Match != nil:
+		return details.Match.Metadata
+	case details.Interface != nil:
+		return details.Interface.Metadata
 	}
 	return nil
 }
 
-// readModuleName is a terrible hack for yanking the module from go.mod file.
-// Should be replaced with something that actually understands go...
-func readModuleName() (string, error) {
-	modFile, err := os.Open("./go.mod")
+// filterByProtocol returns the matches of signatures for the protocol or that
+// don't have a protocol at all.
+func filterByProtocol(matches []detect.FunctionDetails, protocol string) []detect.FunctionDetails {
+	var ret []detect.FunctionDetails
+	for i := range matches {
+		if p := metadata(&matches[i])[protocolMetadata]; p == "" || p == protocol {
+			ret = append(ret, matches[i])
+		}
+	}
+	return ret
+}
+
+// names splits a comma separated list of names, or returns def if there are
+// none.
+func names(list, def string) []string {
+	var ret []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	if len(ret) == 0 {
+		return []string{def}
+	}
+	return ret
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// readPlanTemplate reads the plan template from a file or URL.
+func readPlanTemplate(location string) (string, error) {
+	if !isURL(location) {
+		body, err := ioutil.ReadFile(location)
+		return string(body), err
+	}
+	resp, err := http.Get(location)
 	if err != nil {
 		return "", err
 	}
-	defer modFile.Close()
-	scanner := bufio.NewScanner(modFile)
-	for scanner.Scan() {
-		pieces := strings.Split(scanner.Text(), " ")
-		fmt.Printf("FOund pieces as %+v\n", pieces)
-		if len(pieces) >= 2 && pieces[0] == "module" {
-			return pieces[1], nil
-		}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to fetch %s: %s", location, resp.Status)
 	}
-	return "", nil
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+func TestAlternatives(t *testing.T) {
+	match := func(name string, line, priority int) detect.FunctionDetails {
+		return detect.FunctionDetails{
+			Name:     name,
+			Position: token.Position{Filename: "fn.go", Line: line},
+			Match:    &detect.FunctionSignature{SignatureInfo: detect.SignatureInfo{Priority: priority}},
+		}
+	}
+	matches := []detect.FunctionDetails{
+		match("Low", 1, 0),
+		match("Selected", 2, 10),
+		match("High", 3, 5),
+		match("AlsoLow", 4, 0),
+	}
+	got := alternatives(matches, &matches[1])
+	want := []string{"High", "Low", "AlsoLow"}
+	if len(got) != len(want) {
+		t.Fatalf("Wanted %v, got %+v", want, got)
+	}
+	for i := range want {
+		if got[i].Name != want[i] {
+			t.Errorf("Error at %d, wanted %s, got %s", i, want[i], got[i].Name)
+		}
+	}
+}
+
+func TestPlanFor(t *testing.T) {
+	details := &detect.FunctionDetails{
+		Kind:     detect.KindFunction,
+		Name:     "Receive",
+		Package:  "example.com/app",
+		Receiver: "Server",
+		Match: &detect.FunctionSignature{SignatureInfo: detect.SignatureInfo{
+			ID:       "cloudevents",
+			Metadata: map[string]string{planMetadata: "cloudevents-go-function", requiresMetadata: "cloudevents-go-function,go"},
+		}},
+	}
+	plan, err := planFor(details, "")
+	if err != nil {
+		t.Fatalf("Failed to get the plan: %s", err)
+	}
+	if plan.Provides[0].Name != "cloudevents-go-function" || len(plan.Requires) != 2 || plan.Requires[1].Name != "go" {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if plan.Requires[0].Metadata["receiver"] != "Server" {
+		t.Errorf("Wanted the receiver in the metadata, got %v", plan.Requires[0].Metadata)
+	}
+
+	if _, err := planFor(details, "/does/not/exist.toml"); err == nil {
+		t.Error("Wanted an error for a missing plan template")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+)
+
+// BuildPlan is what detect writes to the build plan file, see
+// https://github.com/buildpacks/spec/blob/main/buildpack.md#build-plan-toml
+// The first alternative is the top level provides and requires, the others
+// are tried in order if it can't be satisfied.
+type BuildPlan struct {
+	PlanAlternative
+	Or []PlanAlternative `toml:"or,omitempty"`
+}
+
+// PlanAlternative is a set of provides and requires that has to be
+// satisfied as a whole.
+type PlanAlternative struct {
+	Provides []PlanProvide `toml:"provides,omitempty"`
+	Requires []PlanRequire `toml:"requires,omitempty"`
+}
+
+type PlanProvide struct {
+	Name string `toml:"name"`
+}
+
+type PlanRequire struct {
+	Name     string                 `toml:"name"`
+	Metadata map[string]interface{} `toml:"metadata,omitempty"`
+}
+
+// defaultPlan returns the plan for the function described by args, which
+// provides and requires the names of args. The build step reads the
+// function from the metadata of the requires, including how to create the
+// instance a method is called on.
+func defaultPlan(args *PlanArguments) *BuildPlan {
+	plan := &BuildPlan{}
+	for _, name := range args.Provides {
+		plan.Provides = append(plan.Provides, PlanProvide{Name: name})
+	}
+	for _, name := range args.Requires {
+		metadata := map[string]interface{}{
+			"package":    args.Package,
+			"function":   args.Function,
+			"kind":       args.Kind,
+			"signature":  args.ID,
+			"exampleEnv": args.Env["PWD"],
+		}
+		if args.Receiver != "" {
+			metadata["receiver"] = args.Receiver
+			metadata["pointerReceiver"] = args.PointerReceiver
+		}
+		if args.Constructor != "" {
+			metadata["constructor"] = args.Constructor
+			metadata["constructorError"] = args.ConstructorError
+		}
+		if args.FactoryError {
+			metadata["factoryError"] = true
+		}
+		if len(args.Params) > 0 {
+			metadata["params"] = args.Params
+		}
+		plan.Requires = append(plan.Requires, PlanRequire{Name: name, Metadata: metadata})
+	}
+	return plan
+}
+
+// templatePlan executes the plan template with args and parses the result,
+// so that it can be validated and written like the default plan.
+func templatePlan(planTemplate string, args *PlanArguments) (*BuildPlan, error) {
+	t, err := template.New("Plan").Parse(planTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the plan template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, args); err != nil {
+		return nil, fmt.Errorf("failed to execute the plan template: %w", err)
+	}
+	plan := &BuildPlan{}
+	md, err := toml.Decode(buf.String(), plan)
+	if err != nil {
+		return nil, fmt.Errorf("the plan template does not produce valid TOML: %w", err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("the plan template produces unknown keys: %s", strings.Join(keys, ", "))
+	}
+	return plan, nil
+}
+
+// addAlternatives appends the alternatives of other to the ones of p,
+// leaving out the ones that provide and require the same names as one that
+// p already has, since the same buildpacks would satisfy them.
+func (p *BuildPlan) addAlternatives(other *BuildPlan) {
+	seen := map[string]bool{p.PlanAlternative.names(): true}
+	for i := range p.Or {
+		seen[p.Or[i].names()] = true
+	}
+	for _, a := range append([]PlanAlternative{other.PlanAlternative}, other.Or...) {
+		if !seen[a.names()] {
+			seen[a.names()] = true
+			p.Or = append(p.Or, a)
+		}
+	}
+}
+
+// names returns the names the alternative provides and requires, as a
+// string that can be compared.
+func (a *PlanAlternative) names() string {
+	var provides, requires []string
+	for _, p := range a.Provides {
+		provides = append(provides, p.Name)
+	}
+	for _, r := range a.Requires {
+		requires = append(requires, r.Name)
+	}
+	sort.Strings(provides)
+	sort.Strings(requires)
+	return strings.Join(provides, ",") + ";" + strings.Join(requires, ",")
+}
+
+// validate checks that each of the alternatives of the plan requires or
+// provides something and that all the entries are named.
+func (p *BuildPlan) validate() error {
+	if err := p.PlanAlternative.validate(); err != nil {
+		return err
+	}
+	for i := range p.Or {
+		if err := p.Or[i].validate(); err != nil {
+			return fmt.Errorf("alternative %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (a *PlanAlternative) validate() error {
+	if len(a.Provides) == 0 && len(a.Requires) == 0 {
+		return errors.New("plan neither provides nor requires anything")
+	}
+	provided := make(map[string]bool)
+	for i, p := range a.Provides {
+		if p.Name == "" {
+			return fmt.Errorf("provides %d has no name", i+1)
+		}
+		if provided[p.Name] {
+			return fmt.Errorf("%q is provided more than once", p.Name)
+		}
+		provided[p.Name] = true
+	}
+	for i, r := range a.Requires {
+		if r.Name == "" {
+			return fmt.Errorf("requires %d has no name", i+1)
+		}
+	}
+	return nil
+}
+
+// write validates the plan and writes it to planFileName, replacing
+// whatever is in there.
+func (p *BuildPlan) write(planFileName string) error {
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("invalid build plan: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(p); err != nil {
+		return err
+	}
+	return os.WriteFile(planFileName, buf.Bytes(), 0644)
+}
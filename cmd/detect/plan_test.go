@@ -0,0 +1,181 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+func testArguments() *PlanArguments {
+	return &PlanArguments{
+		Name:     "http-go-function",
+		Package:  "example.com/app",
+		Function: "Handle",
+		Kind:     "function",
+		ID:       "http",
+		Provides: []string{"http-go-function"},
+		Requires: []string{"http-go-function", "go"},
+		Env:      map[string]string{"PWD": "/workspace"},
+	}
+}
+
+func TestDefaultPlan(t *testing.T) {
+	args := testArguments()
+	args.Receiver, args.PointerReceiver = "Server", true
+	args.Constructor, args.ConstructorError = "NewServer", true
+	args.Params = []detect.FunctionArg{{ImportPath: "net/http", Name: "ResponseWriter"}}
+	plan := defaultPlan(args)
+	if err := plan.validate(); err != nil {
+		t.Fatalf("Invalid default plan: %s", err)
+	}
+	if len(plan.Provides) != 1 || len(plan.Requires) != 2 || len(plan.Or) != 0 {
+		t.Fatalf("Unexpected plan %+v", plan)
+	}
+	meta := plan.Requires[1].Metadata
+	for key, want := range map[string]interface{}{
+		"package":          "example.com/app",
+		"function":         "Handle",
+		"signature":        "http",
+		"receiver":         "Server",
+		"pointerReceiver":  true,
+		"constructor":      "NewServer",
+		"constructorError": true,
+		"exampleEnv":       "/workspace",
+	} {
+		if meta[key] != want {
+			t.Errorf("Wanted %s to be %v, got %v", key, want, meta[key])
+		}
+	}
+	if _, ok := meta["factoryError"]; ok {
+		t.Error("Wanted no factoryError for a method")
+	}
+}
+
+func TestTemplatePlan(t *testing.T) {
+	plan, err := templatePlan(`[[provides]]
+name = "{{ .Name }}"
+
+[[requires]]
+name = "{{ .Name }}"
+[requires.metadata]
+function = "{{ .Function }}"
+
+[[or]]
+[[or.requires]]
+name = "go"
+`, testArguments())
+	if err != nil {
+		t.Fatalf("Failed to use the template: %s", err)
+	}
+	if plan.Requires[0].Metadata["function"] != "Handle" || len(plan.Or) != 1 || plan.Or[0].Requires[0].Name != "go" {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+
+	for template, want := range map[string]string{
+		"{{ .Missing }}":                      "failed to execute the plan template",
+		"{{ .Name ":                           "failed to parse the plan template",
+		"[[provides]\n":                       "does not produce valid TOML",
+		"[[provides]]\nname = \"a\"\nx = 1\n": "unknown keys: provides.x",
+	} {
+		if _, err := templatePlan(template, testArguments()); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Wanted error %q for %q, got %v", want, template, err)
+		}
+	}
+}
+
+func TestReadPlanTemplate(t *testing.T) {
+	const template = "[[provides]]\nname = \"{{ .Name }}\"\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plan.toml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(template))
+	}))
+	defer server.Close()
+
+	got, err := readPlanTemplate(server.URL + "/plan.toml")
+	if err != nil || got != template {
+		t.Errorf("Wanted the template, got %q, %v", got, err)
+	}
+	if got, err := readPlanTemplate(server.URL + "/missing.toml"); err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("Wanted a 404 error, got %q, %v", got, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "plan.toml")
+	if err := os.WriteFile(file, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readPlanTemplate(file); err != nil || got != template {
+		t.Errorf("Wanted the template from the file, got %q, %v", got, err)
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	named := PlanAlternative{Provides: []PlanProvide{{Name: "a"}}}
+	tests := []struct {
+		name string
+		plan BuildPlan
+		err  string
+	}{
+		{name: "valid", plan: BuildPlan{PlanAlternative: named, Or: []PlanAlternative{named}}},
+		{name: "empty", plan: BuildPlan{}, err: "plan neither provides nor requires anything"},
+		{name: "unnamed provides", plan: BuildPlan{PlanAlternative: PlanAlternative{Provides: []PlanProvide{{}}}}, err: "provides 1 has no name"},
+		{name: "unnamed requires", plan: BuildPlan{PlanAlternative: PlanAlternative{Requires: []PlanRequire{{Name: "a"}, {}}}}, err: "requires 2 has no name"},
+		{name: "provided twice", plan: BuildPlan{PlanAlternative: PlanAlternative{Provides: []PlanProvide{{Name: "a"}, {Name: "a"}}}}, err: `"a" is provided more than once`},
+		{name: "empty alternative", plan: BuildPlan{PlanAlternative: named, Or: []PlanAlternative{named, {}}}, err: "alternative 2: plan neither provides nor requires anything"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.plan.validate()
+			if test.err == "" && err != nil {
+				t.Errorf("Wanted a valid plan, got %s", err)
+			}
+			if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Errorf("Wanted error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestAddAlternatives(t *testing.T) {
+	http := defaultPlan(testArguments())
+	args := testArguments()
+	args.Name, args.Provides, args.Requires = "cloudevents-go-function", []string{"cloudevents-go-function"}, []string{"cloudevents-go-function"}
+	events := defaultPlan(args)
+
+	// The same plan again, for another function, adds nothing.
+	http.addAlternatives(defaultPlan(testArguments()))
+	http.addAlternatives(events)
+	http.addAlternatives(events)
+	if len(http.Or) != 1 || http.Or[0].Provides[0].Name != "cloudevents-go-function" {
+		t.Errorf("Wanted the cloudevents plan as the only alternative, got %+v", http.Or)
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.toml")
+	if err := (&BuildPlan{}).write(file); err == nil || !strings.HasPrefix(err.Error(), "invalid build plan") {
+		t.Errorf("Wanted an invalid build plan, got %v", err)
+	}
+	if err := defaultPlan(testArguments()).write(file); err != nil {
+		t.Fatalf("Failed to write the plan: %s", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan BuildPlan
+	if _, err := toml.Decode(string(data), &plan); err != nil {
+		t.Fatalf("Failed to read back the plan: %s", err)
+	}
+	if plan.Requires[0].Metadata["function"] != "Handle" {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// detectArgs returns the platform directory and the build plan path. Since
+// buildpack API 0.8 they are passed as CNB_PLATFORM_DIR and
+// CNB_BUILD_PLAN_PATH, before that as the arguments.
+func detectArgs(args []string) (string, string, error) {
+	platformDir, planFileName := os.Getenv("CNB_PLATFORM_DIR"), os.Getenv("CNB_BUILD_PLAN_PATH")
+	if len(args) >= 2 {
+		if platformDir == "" {
+			platformDir = args[0]
+		}
+		if planFileName == "" {
+			planFileName = args[1]
+		}
+	}
+	if platformDir == "" || planFileName == "" {
+		return "", "", errors.New("the platform directory and the build plan path are required")
+	}
+	return platformDir, planFileName, nil
+}
+
+// loadPlatformEnv sets the environment variables the platform provides as
+// files in <platformDir>/env, where the name of the file is the name of the
+// variable and the content is its value. Variables that are already set,
+// which is what the lifecycle does unless clear-env is set, are left alone.
+func loadPlatformEnv(platformDir string) error {
+	envDir := filepath.Join(platformDir, "env")
+	entries, err := os.ReadDir(envDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(envDir, name))
+		if err != nil {
+			return err
+		}
+		if err := os.Setenv(name, string(value)); err != nil {
+			return fmt.Errorf("failed to set %s from %s: %w", name, envDir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		platform  string
+		plan      string
+		wantError bool
+	}{
+		{name: "arguments", args: []string{"/platform", "/plan.toml"}, platform: "/platform", plan: "/plan.toml"},
+		{
+			name:     "environment",
+			env:      map[string]string{"CNB_PLATFORM_DIR": "/env-platform", "CNB_BUILD_PLAN_PATH": "/env-plan.toml"},
+			args:     []string{"/platform", "/plan.toml"},
+			platform: "/env-platform",
+			plan:     "/env-plan.toml",
+		},
+		{
+			name:     "mixed",
+			env:      map[string]string{"CNB_BUILD_PLAN_PATH": "/env-plan.toml"},
+			args:     []string{"/platform", "/plan.toml"},
+			platform: "/platform",
+			plan:     "/env-plan.toml",
+		},
+		{name: "missing", args: []string{"/platform"}, wantError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("CNB_PLATFORM_DIR", test.env["CNB_PLATFORM_DIR"])
+			t.Setenv("CNB_BUILD_PLAN_PATH", test.env["CNB_BUILD_PLAN_PATH"])
+			platform, plan, err := detectArgs(test.args)
+			if test.wantError {
+				if err == nil {
+					t.Errorf("Wanted an error, got %q and %q", platform, plan)
+				}
+				return
+			}
+			if err != nil || platform != test.platform || plan != test.plan {
+				t.Errorf("Wanted %q and %q, got %q and %q, %v", test.platform, test.plan, platform, plan, err)
+			}
+		})
+	}
+}
+
+func TestLoadPlatformEnv(t *testing.T) {
+	platformDir := t.TempDir()
+	envDir := filepath.Join(platformDir, "env")
+	if err := os.MkdirAll(filepath.Join(envDir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{
+		"DETECT_TEST_FUNCTION": "Handle",
+		"DETECT_TEST_SET":      "from the platform",
+	} {
+		if err := os.WriteFile(filepath.Join(envDir, name), []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DETECT_TEST_SET", "already set")
+	// Make sure the variable is unset, and restored afterwards.
+	t.Setenv("DETECT_TEST_FUNCTION", "")
+	os.Unsetenv("DETECT_TEST_FUNCTION")
+
+	if err := loadPlatformEnv(platformDir); err != nil {
+		t.Fatalf("Failed to load the platform env: %s", err)
+	}
+	if got := os.Getenv("DETECT_TEST_FUNCTION"); got != "Handle" {
+		t.Errorf("Wanted DETECT_TEST_FUNCTION from the platform, got %q", got)
+	}
+	if got := os.Getenv("DETECT_TEST_SET"); got != "already set" {
+		t.Errorf("Wanted DETECT_TEST_SET to be left alone, got %q", got)
+	}
+	if _, ok := os.LookupEnv("nested"); ok {
+		t.Error("Wanted directories to be skipped")
+	}
+
+	// Platforms don't have to provide any env.
+	if err := loadPlatformEnv(t.TempDir()); err != nil {
+		t.Errorf("Wanted no error without an env directory, got %s", err)
+	}
+}
@@ -0,0 +1,15 @@
+// Command functypes reports the functions matching the supported function
+// signatures. It can be run on its own or with go vet:
+//
+//	go vet -vettool=$(which functypes) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/vaikas/gofunctypechecker/pkg/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,40 @@
+// Command gensignatures writes the signatures config for func types,
+// functions and interfaces declared in a Go package, for example:
+//
+//	gensignatures -dir ./pkg/handlers -format yaml HandlerFunc EventHandler
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package declaring the types")
+	format := flag.String("format", string(detect.YAML), "format of the config, one of json, yaml or toml")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <name>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	sigFormat, err := detect.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid -format : %s", err)
+	}
+
+	sigs, err := detect.GenerateSignatures(*dir, flag.Args()...)
+	if err != nil {
+		log.Fatalf("Failed to generate signatures: %s", err)
+	}
+	if err := sigs.Encode(os.Stdout, sigFormat); err != nil {
+		log.Fatalf("Failed to write signatures: %s", err)
+	}
+}
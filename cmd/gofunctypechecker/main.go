@@ -0,0 +1,79 @@
+// Command gofunctypechecker finds the functions matching the supported
+// function signatures, for use locally or in CI. For example:
+//
+//	gofunctypechecker scan --signatures signatures.yaml ./...
+//
+// The exit code tells the outcome, see the exit* constants.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Exit codes of the commands.
+const (
+	// exitOK is returned when a command that doesn't look for functions,
+	// like validate or help, succeeded.
+	exitOK = 0
+	// exitMatch is returned when a matching function was found.
+	exitMatch = 0
+	// exitNoMatch is returned when no matching function was found.
+	exitNoMatch = 1
+	// exitAmbiguous is returned when more than one function matches where
+	// only a single one is expected.
+	exitAmbiguous = 2
+	// exitError is returned for invalid arguments and failures to read the
+	// signatures or the source.
+	exitError = 3
+)
+
+// command runs a subcommand with the arguments following its name and
+// returns the exit code.
+type command struct {
+	description string
+	run         func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"scan":     {"print the functions matching the signatures", runScan},
+	"validate": {"check the signature configs against the schema", runValidate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return exitError
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+	"github.com/vaikas/gofunctypechecker/pkg/sarif"
+)
+
+// errNoMatch is reported when no function matches.
+var errNoMatch = errors.New("no matching function found")
+
+// scanResult is what scan prints in the json and yaml formats.
+type scanResult struct {
+	Matches     []scanMatch      `json:"matches"`
+	Diagnostics []scanDiagnostic `json:"diagnostics,omitempty"`
+	// Warnings are the problems with declarations that could not be
+	// checked.
+	Warnings []string `json:"warnings,omitempty"`
+	// Error is set if the scan failed or is ambiguous.
+	Error string `json:"error,omitempty"`
+}
+
+type scanMatch struct {
+	Kind      detect.Kind `json:"kind"`
+	Name      string      `json:"name"`
+	Package   string      `json:"package"`
+	Position  string      `json:"position"`
+	Signature string      `json:"signature"`
+	// ID is the id of the signature or interface that matched, if it has
+	// one.
+	ID string `json:"id,omitempty"`
+	// Receiver is the type of a method, which has a pointer receiver if
+	// PointerReceiver is set. For types PointerReceiver is set if only a
+	// pointer implements the interface.
+	Receiver        string `json:"receiver,omitempty"`
+	PointerReceiver bool   `json:"pointerReceiver,omitempty"`
+}
+
+// qualifiedName returns the name of the match qualified by its package, and
+// by its receiver for methods, like in pkg.(*Server).Receive.
+func (m *scanMatch) qualifiedName() string {
+	switch {
+	case m.Receiver == "":
+		return m.Package + "." + m.Name
+	case m.PointerReceiver:
+		return fmt.Sprintf("%s.(*%s).%s", m.Package, m.Receiver, m.Name)
+	}
+	return fmt.Sprintf("%s.%s.%s", m.Package, m.Receiver, m.Name)
+}
+
+type scanDiagnostic struct {
+	Name      string   `json:"name"`
+	Position  string   `json:"position"`
+	Signature string   `json:"signature"`
+	Reasons   []string `json:"reasons"`
+}
+
+func runScan(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	signatures := flags.String("signatures", "", "file or URL to read the function signatures from, the HTTP handler signature by default")
+	signaturesFormat := flags.String("signatures-format", "", "format of the signatures, one of json, yaml or toml, by default inferred from the extension, content type or content")
+	function := flags.String("function", "", "only consider the functions with this name")
+	format := flags.String("format", "text", "output format, one of text, json, yaml or sarif")
+	all := flags.Bool("all", false, "print all the matches instead of expecting a single one")
+	typeCheck := flags.Bool("type-check", false, "resolve the types with go/types, needed for example for variables of named func types like http.HandlerFunc")
+	factories := flags.Bool("factories", false, "also report functions returning a matching function")
+	verbose := flags.Bool("v", false, "log the signatures that are tried to stderr")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: scan [flags] [packages...]\n\n")
+		fmt.Fprintf(stderr, "The packages are directories, directories ending in /... for all the packages\n")
+		fmt.Fprintf(stderr, "below them or single .go files. The default is the package in the current directory.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	switch *format {
+	case "text", "json", "yaml", "sarif":
+	default:
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return exitError
+	}
+
+	sigFormat, err := parseFormat(*signaturesFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	// Like cmd/detect, only what can be called from another package.
+	opts := []detect.Option{detect.WithExportedOnly()}
+	if *typeCheck {
+		opts = append(opts, detect.WithTypeChecking())
+	}
+	if *factories {
+		opts = append(opts, detect.WithFactories())
+	}
+	if *verbose {
+		opts = append(opts, detect.WithLogger(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	}
+	d, err := newDetector(*signatures, sigFormat, opts...)
+	if err != nil {
+		return printScan(stdout, stderr, *format, nil, nil, nil, fmt.Errorf("failed to read the signatures: %w", err), exitError)
+	}
+	pkgs, err := scanPackages(d, flags.Args())
+	if err != nil {
+		return printScan(stdout, stderr, *format, nil, nil, nil, err, exitError)
+	}
+
+	var matches []detect.FunctionDetails
+	var diags []detect.Diagnostic
+	var warnings []error
+	for _, p := range pkgs {
+		warnings = append(warnings, p.Errors...)
+		for i := range p.Matches {
+			if *function == "" || p.Matches[i].Name == *function {
+				matches = append(matches, p.Matches[i])
+			}
+		}
+		for i := range p.Diagnostics {
+			if *function == "" || p.Diagnostics[i].Name == *function {
+				diags = append(diags, p.Diagnostics[i])
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		return printScan(stdout, stderr, *format, matches, diags, warnings, errNoMatch, exitNoMatch)
+	case len(matches) > 1 && !*all:
+		return printScan(stdout, stderr, *format, matches, diags, warnings, &detect.AmbiguityError{Matches: matches}, exitAmbiguous)
+	}
+	return printScan(stdout, stderr, *format, matches, diags, warnings, nil, exitMatch)
+}
+
+func newDetector(signatures string, format detect.Format, opts ...detect.Option) (*detect.Detector, error) {
+	if signatures == "" {
+		return detect.NewDetector(detect.DefaultSignatures, opts...), nil
+	}
+	c, err := loadConfig(signatures, format)
+	if err != nil {
+		return nil, err
+	}
+	return detect.NewDetectorFromConfig(c, opts...), nil
+}
+
+// loadConfig reads the signatures config from a file or URL. If format is
+// empty it's inferred, see detect.LoadConfigFile and detect.LoadConfigURL.
+func loadConfig(location string, format detect.Format) (*detect.Config, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return detect.LoadConfigURL(location, format)
+	}
+	return detect.LoadConfigFile(location, format)
+}
+
+// parseFormat parses the format of the signatures given as a flag, where
+// the empty string means it's inferred.
+func parseFormat(s string) (detect.Format, error) {
+	if s == "" {
+		return "", nil
+	}
+	return detect.ParseFormat(s)
+}
+
+// scanPackages scans the packages given as arguments, see the usage of the
+// scan command.
+func scanPackages(d *detect.Detector, args []string) ([]*detect.Package, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	var pkgs []*detect.Package
+	for _, arg := range args {
+		switch {
+		case arg == "..." || strings.HasSuffix(arg, "/..."):
+			root := strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+			if root == "" {
+				root = "."
+			}
+			found, err := d.ScanModule(root)
+			if err != nil {
+				return nil, err
+			}
+			pkgs = append(pkgs, found...)
+		case strings.HasSuffix(arg, ".go"):
+			p, err := d.ScanFile(arg)
+			if err != nil {
+				return nil, err
+			}
+			pkgs = append(pkgs, p)
+		default:
+			p, err := d.ScanPackage(arg)
+			if err != nil {
+				return nil, err
+			}
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs, nil
+}
+
+func toScanMatch(m *detect.FunctionDetails) scanMatch {
+	ret := scanMatch{
+		Kind:      m.Kind,
+		Name:      m.Name,
+		Package:   m.Package,
+		Position:  m.Position.String(),
+		Signature: m.Signature,
+
+		Receiver:        m.Receiver,
+		PointerReceiver: m.PointerReceiver,
+	}
+	switch {
+	case m.Match != nil:
+		ret.ID = m.Match.ID
+	case m.Interface != nil:
+		ret.ID = m.Interface.ID
+	}
+	return ret
+}
+
+// printScan prints the matches, near misses and warnings in the format,
+// along with err if there is one, and returns code.
+func printScan(stdout, stderr io.Writer, format string, matches []detect.FunctionDetails, diags []detect.Diagnostic, warnings []error, err error, code int) int {
+	switch format {
+	case "json", "yaml":
+		result := &scanResult{Matches: []scanMatch{}}
+		for i := range matches {
+			result.Matches = append(result.Matches, toScanMatch(&matches[i]))
+		}
+		for i := range diags {
+			result.Diagnostics = append(result.Diagnostics, scanDiagnostic{
+				Name:      diags[i].Name,
+				Position:  diags[i].Position.String(),
+				Signature: diags[i].Signature,
+				Reasons:   diags[i].Reasons,
+			})
+		}
+		for _, w := range warnings {
+			result.Warnings = append(result.Warnings, w.Error())
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		var data []byte
+		var merr error
+		if format == "json" {
+			data, merr = json.MarshalIndent(result, "", "  ")
+			data = append(data, '\n')
+		} else {
+			data, merr = yaml.Marshal(result)
+		}
+		if merr != nil {
+			fmt.Fprintln(stderr, merr)
+			return exitError
+		}
+		stdout.Write(data)
+	case "sarif":
+		// Paths are reported relative to the current directory, which is
+		// usually the root of the repository in CI.
+		report := sarif.NewReport(".")
+		report.AddMatches(matches)
+		report.AddDiagnostics(diags)
+		for _, w := range warnings {
+			report.AddError(w)
+		}
+		if err != nil && err != errNoMatch {
+			report.AddError(err)
+		}
+		if werr := report.Write(stdout); werr != nil {
+			fmt.Fprintln(stderr, werr)
+			return exitError
+		}
+	default:
+		for i := range matches {
+			m := toScanMatch(&matches[i])
+			if m.ID != "" {
+				fmt.Fprintf(stdout, "%s: %s %s matches %s [%s]\n", m.Position, m.Kind, m.qualifiedName(), m.Signature, m.ID)
+			} else {
+				fmt.Fprintf(stdout, "%s: %s %s matches %s\n", m.Position, m.Kind, m.qualifiedName(), m.Signature)
+			}
+		}
+		// The near misses explain why nothing matched.
+		if len(matches) == 0 {
+			for i := range diags {
+				fmt.Fprintln(stdout, diags[i].String())
+			}
+		}
+		for _, w := range warnings {
+			fmt.Fprintln(stderr, w)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+		}
+	}
+	return code
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const testdata = "../../pkg/detect/testdata/"
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		// stdout and stderr are expected to be part of the output.
+		stdout string
+		stderr string
+	}{{
+		name:   "match",
+		args:   []string{testdata + "f1.go"},
+		code:   exitMatch,
+		stdout: "f1.go:8:6: function github.com/vaikas/gofunctypechecker/pkg/detect/testdata.Receive matches func(http.ResponseWriter, *http.Request) [http]",
+	}, {
+		name:   "no match",
+		args:   []string{testdata + "diagnostics"},
+		code:   exitNoMatch,
+		stdout: "near.go:10:6: Receive does not match func(http.ResponseWriter, *http.Request): arg 2 is `http.Request`, expected `*http.Request`",
+		stderr: "no matching function found",
+	}, {
+		name:   "function",
+		args:   []string{"-function", "Other", testdata + "f1.go"},
+		code:   exitNoMatch,
+		stderr: "no matching function found",
+	}, {
+		name:   "ambiguous",
+		args:   []string{"-signatures", testdata + "signatures.yaml", testdata + "multi-fn.go"},
+		code:   exitAmbiguous,
+		stderr: "Found 2 matching signatures, expecting 1",
+	}, {
+		name:   "all",
+		args:   []string{"-signatures", testdata + "signatures.yaml", "-all", testdata + "multi-fn.go"},
+		code:   exitMatch,
+		stdout: "ReceiveEvent matches",
+	}, {
+		name:   "unknown format",
+		args:   []string{"-format", "xml", testdata + "f1.go"},
+		code:   exitError,
+		stderr: `unsupported format "xml"`,
+	}, {
+		name:   "unknown signatures format",
+		args:   []string{"-signatures-format", "xml", testdata + "f1.go"},
+		code:   exitError,
+		stderr: `unknown format "xml"`,
+	}, {
+		name:   "missing signatures",
+		args:   []string{"-signatures", testdata + "missing.yaml", testdata + "f1.go"},
+		code:   exitError,
+		stderr: "failed to read the signatures",
+	}, {
+		name:   "missing package",
+		args:   []string{testdata + "missing"},
+		code:   exitError,
+		stderr: "missing",
+	}, {
+		name: "help",
+		args: []string{"-h"},
+		code: exitOK,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"scan"}, test.args...), &stdout, &stderr)
+			if code != test.code {
+				t.Errorf("Wanted exit code %d, got %d, stderr:\n%s", test.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), test.stdout) {
+				t.Errorf("Wanted %q in stdout, got:\n%s", test.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), test.stderr) {
+				t.Errorf("Wanted %q in stderr, got:\n%s", test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestScanFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []string
+		code   int
+		// unmarshal decodes the output.
+		unmarshal func([]byte, any) error
+		// check checks the decoded output.
+		check func(t *testing.T, out map[string]any)
+	}{{
+		format:    "json",
+		args:      []string{testdata + "f1.go"},
+		code:      exitMatch,
+		unmarshal: json.Unmarshal,
+		check: func(t *testing.T, out map[string]any) {
+			matches, _ := out["matches"].([]any)
+			if len(matches) != 1 || matches[0].(map[string]any)["name"] != "Receive" {
+				t.Errorf("Wanted Receive to match, got %v", out)
+			}
+		},
+	}, {
+		format:    "yaml",
+		args:      []string{testdata + "diagnostics"},
+		code:      exitNoMatch,
+		unmarshal: func(data []byte, v any) error { return yaml.Unmarshal(data, v) },
+		check: func(t *testing.T, out map[string]any) {
+			diags, _ := out["diagnostics"].([]any)
+			if len(diags) != 3 || out["error"] != "no matching function found" {
+				t.Errorf("Wanted 3 near misses and an error, got %v", out)
+			}
+		},
+	}, {
+		format:    "sarif",
+		args:      []string{testdata + "f1.go"},
+		code:      exitMatch,
+		unmarshal: json.Unmarshal,
+		check: func(t *testing.T, out map[string]any) {
+			runs, _ := out["runs"].([]any)
+			if out["version"] != "2.1.0" || len(runs) != 1 {
+				t.Fatalf("Wanted a SARIF report with one run, got %v", out)
+			}
+			results, _ := runs[0].(map[string]any)["results"].([]any)
+			if len(results) != 1 || results[0].(map[string]any)["ruleId"] != "match/http" {
+				t.Errorf("Wanted the match as the result, got %v", results)
+			}
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"scan", "-format", test.format}, test.args...), &stdout, &stderr)
+			if code != test.code {
+				t.Errorf("Wanted exit code %d, got %d, stderr:\n%s", test.code, code, stderr.String())
+			}
+			out := map[string]any{}
+			if err := test.unmarshal(stdout.Bytes(), &out); err != nil {
+				t.Fatalf("Failed to decode the %s output: %s\n%s", test.format, err, stdout.String())
+			}
+			test.check(t, out)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "format of the signatures, one of json, yaml or toml, by default inferred from the extension, content type or content")
+	schema := flags.Bool("schema", false, "print the JSON Schema of the signatures instead")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: validate [flags] <signatures files or URLs...>\n\n")
+		fmt.Fprintf(stderr, "Checks that the configs are valid signature configs and prints the format\n")
+		fmt.Fprintf(stderr, "(json, yaml or toml) they were parsed as.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if *schema {
+		stdout.Write(detect.Schema())
+		return exitOK
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	sigFormat, err := parseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	code := exitOK
+	for _, file := range flags.Args() {
+		if err := validateConfig(stdout, file, sigFormat); err != nil {
+			var verr *detect.ValidationError
+			if errors.As(err, &verr) {
+				for _, p := range verr.Problems {
+					printProblem(stderr, file, p)
+				}
+			} else {
+				// The errors already name the file or URL.
+				fmt.Fprintln(stderr, err)
+			}
+			code = exitError
+		}
+	}
+	return code
+}
+
+// validateConfig checks the signatures config in the file or URL,
+// including the signatures given in Go syntax which the schema can't check.
+func validateConfig(stdout io.Writer, location string, format detect.Format) error {
+	c, err := loadConfig(location, format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: valid %s\n", location, c.Format)
+	return nil
+}
+
+// printProblem prints the problem as file:line: path: message, which
+// editors can jump to.
+func printProblem(w io.Writer, file string, p detect.Problem) {
+	pos := file
+	if p.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", file, p.Line)
+	}
+	if p.Path != "" {
+		fmt.Fprintf(w, "%s: %s: %s\n", pos, p.Path, p.Message)
+		return
+	}
+	fmt.Fprintf(w, "%s: %s\n", pos, p.Message)
+}
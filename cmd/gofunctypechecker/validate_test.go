@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"bad.yaml": `functionSignatures:
+  - in:
+      - importpath: net/http
+        name: ResponseWriter
+`,
+		"bad.json": `{
+  "functionSignatures": [
+    {"in": [{"name": "int", "slice": "yes"}]}
+  ]
+}`,
+		"bad.toml": `[[functionSignatures]]
+[[functionSignatures.in]]
+name = "int"
+channel = "RECV"
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		// stdout and stderr are expected to be part of the output.
+		stdout string
+		stderr string
+	}{{
+		name:   "json",
+		args:   []string{testdata + "signatures.json"},
+		code:   exitOK,
+		stdout: testdata + "signatures.json: valid json",
+	}, {
+		name:   "yaml",
+		args:   []string{testdata + "signatures.yaml"},
+		code:   exitOK,
+		stdout: testdata + "signatures.yaml: valid yaml",
+	}, {
+		name:   "toml",
+		args:   []string{testdata + "signatures.toml"},
+		code:   exitOK,
+		stdout: testdata + "signatures.toml: valid toml",
+	}, {
+		name:   "invalid yaml",
+		args:   []string{filepath.Join(dir, "bad.yaml")},
+		code:   exitError,
+		stderr: filepath.Join(dir, "bad.yaml") + `:3: /functionSignatures/0/in/0/importpath: unknown field "importpath"`,
+	}, {
+		name:   "invalid json",
+		args:   []string{filepath.Join(dir, "bad.json")},
+		code:   exitError,
+		stderr: filepath.Join(dir, "bad.json") + ":3: /functionSignatures/0/in/0/slice: expected boolean, but got string",
+	}, {
+		name:   "invalid toml",
+		args:   []string{filepath.Join(dir, "bad.toml")},
+		code:   exitError,
+		stderr: filepath.Join(dir, "bad.toml") + `:4: /functionSignatures/0/in/0/channel: value must be one of "SEND", "RECEIVE", "BOTH"`,
+	}, {
+		name:   "valid and invalid",
+		args:   []string{testdata + "signatures.yaml", filepath.Join(dir, "bad.json")},
+		code:   exitError,
+		stdout: testdata + "signatures.yaml: valid yaml",
+		stderr: filepath.Join(dir, "bad.json") + ":3:",
+	}, {
+		name:   "wrong format",
+		args:   []string{"-format", "toml", testdata + "signatures.yaml"},
+		code:   exitError,
+		stderr: testdata + "signatures.yaml",
+	}, {
+		name:   "unknown format",
+		args:   []string{"-format", "xml", testdata + "signatures.yaml"},
+		code:   exitError,
+		stderr: `unknown format "xml"`,
+	}, {
+		name:   "missing",
+		args:   []string{testdata + "missing.yaml"},
+		code:   exitError,
+		stderr: "missing.yaml",
+	}, {
+		name:   "no files",
+		code:   exitError,
+		stderr: "Usage: validate",
+	}, {
+		name:   "schema",
+		args:   []string{"-schema"},
+		code:   exitOK,
+		stdout: `"functionSignatures"`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"validate"}, test.args...), &stdout, &stderr)
+			if code != test.code {
+				t.Errorf("Wanted exit code %d, got %d, stderr:\n%s", test.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), test.stdout) {
+				t.Errorf("Wanted %q in stdout, got:\n%s", test.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), test.stderr) {
+				t.Errorf("Wanted %q in stderr, got:\n%s", test.stderr, stderr.String())
+			}
+		})
+	}
+}
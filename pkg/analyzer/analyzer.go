@@ -0,0 +1,189 @@
+// Package analyzer exposes the function signature checks of the detect
+// package as an analysis.Analyzer, so that they can be run with go vet,
+// gopls or golangci-lint.
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+const doc = `report functions matching the supported function signatures
+
+The signatures are read from the file or URL given with -signatures, which
+uses the same format as the detect package. By default the HTTP handler
+signature func(http.ResponseWriter, *http.Request) is used. An ambiguity is
+reported if there's more than one matching function per package, along with
+the functions that almost match, with suggested fixes where possible, and
+the functions using types that can't be checked. With -matches each matching
+function is reported as well.`
+
+// Categories of the reported diagnostics.
+const (
+	CategoryMatch       = "match"
+	CategoryAmbiguous   = "ambiguous"
+	CategoryNearMiss    = "near-miss"
+	CategoryUnsupported = "unsupported-type"
+)
+
+// Analyzer checks the functions against the signatures given with the
+// -signatures flag.
+var Analyzer = newFlagAnalyzer()
+
+// NewAnalyzer returns an Analyzer that uses d for matching. It has no flags
+// and, unlike Analyzer with -matches, doesn't report the matches.
+func NewAnalyzer(d *detect.Detector) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "functypes",
+		Doc:  doc,
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return run(pass, d, false)
+		},
+	}
+}
+
+func newFlagAnalyzer() *analysis.Analyzer {
+	var (
+		signatures string
+		format     string
+		factories  bool
+		matches    bool
+		once       sync.Once
+		d          *detect.Detector
+		err        error
+	)
+	a := NewAnalyzer(nil)
+	a.Flags.StringVar(&signatures, "signatures", "", "file or URL to read the function signatures from")
+	a.Flags.StringVar(&format, "signatures-format", "", "format of the signatures, one of json, yaml or toml, by default inferred")
+	a.Flags.BoolVar(&factories, "factories", false, "also report functions returning a matching function")
+	a.Flags.BoolVar(&matches, "matches", false, "report each matching function, not only the problems")
+	a.Run = func(pass *analysis.Pass) (interface{}, error) {
+		// The flags have been parsed by the time the first package is run.
+		once.Do(func() {
+			d, err = newDetector(signatures, format, factories)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return run(pass, d, matches)
+	}
+	return a
+}
+
+func newDetector(signatures, format string, factories bool) (*detect.Detector, error) {
+	opts := []detect.Option{detect.WithExportedOnly()}
+	if factories {
+		opts = append(opts, detect.WithFactories())
+	}
+	if signatures == "" {
+		return detect.NewDetector(detect.DefaultSignatures, opts...), nil
+	}
+	var sigFormat detect.Format
+	var err error
+	if format != "" {
+		if sigFormat, err = detect.ParseFormat(format); err != nil {
+			return nil, err
+		}
+	}
+	var c *detect.Config
+	if strings.HasPrefix(signatures, "http://") || strings.HasPrefix(signatures, "https://") {
+		c, err = detect.LoadConfigURL(signatures, sigFormat)
+	} else {
+		c, err = detect.LoadConfigFile(signatures, sigFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return detect.NewDetectorFromConfig(c, opts...), nil
+}
+
+// run reports the problems with the functions of the package, and the
+// matches if reportMatches is set. A correct function is not a problem, so
+// by default go vet passes for a package with a single match.
+func run(pass *analysis.Pass, d *detect.Detector, reportMatches bool) (interface{}, error) {
+	// Test files (and the test packages) are not part of what gets built.
+	var files []*ast.File
+	for _, f := range pass.Files {
+		if !strings.HasSuffix(pass.Fset.File(f.Pos()).Name(), "_test.go") {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	dir := filepath.Dir(pass.Fset.File(files[0].Pos()).Name())
+	p := d.ScanFiles(pass.Fset, pass.Pkg.Path(), dir, files, pass.TypesInfo)
+
+	if reportMatches {
+		for _, m := range p.Matches {
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos(pass, files, m.Position),
+				Category: CategoryMatch,
+				Message:  fmt.Sprintf("%s %s matches %s", m.Kind, m.Name, m.Signature),
+			})
+		}
+	}
+	if len(p.Matches) > 1 {
+		for _, m := range p.Matches {
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos(pass, files, m.Position),
+				Category: CategoryAmbiguous,
+				Message:  fmt.Sprintf("%s is one of %d matches in the package, expecting 1", m.Name, len(p.Matches)),
+			})
+		}
+	}
+	for _, diag := range p.Diagnostics {
+		at := pos(pass, files, diag.Position)
+		pass.Report(analysis.Diagnostic{
+			Pos:            at,
+			Category:       CategoryNearMiss,
+			Message:        fmt.Sprintf("%s does not match %s: %s", diag.Name, diag.Signature, strings.Join(diag.Reasons, ", ")),
+			SuggestedFixes: suggestedFixes(funcDecl(files, at), diag),
+		})
+	}
+	for _, err := range p.Errors {
+		var unsupported *detect.UnsupportedTypeError
+		if !errors.As(err, &unsupported) {
+			pass.Reportf(files[0].Package, "%s", err)
+			continue
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:      pos(pass, files, unsupported.Position),
+			Category: CategoryUnsupported,
+			Message:  fmt.Sprintf("%s uses unsupported type `%s` and can not be checked", unsupported.Name, unsupported.Type),
+		})
+	}
+	return nil, nil
+}
+
+// pos maps position back to a token.Pos in one of files.
+func pos(pass *analysis.Pass, files []*ast.File, position token.Position) token.Pos {
+	for _, f := range files {
+		tf := pass.Fset.File(f.Pos())
+		if tf.Name() == position.Filename {
+			return tf.Pos(position.Offset)
+		}
+	}
+	return token.NoPos
+}
+
+// funcDecl returns the function whose name is at name or nil.
+func funcDecl(files []*ast.File, name token.Pos) *ast.FuncDecl {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Pos() == name {
+				return fd
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	// A single match is not a problem, and the test files don't add another.
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "single")
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "unsupported")
+}
+
+func TestAnalyzerMatches(t *testing.T) {
+	if err := Analyzer.Flags.Set("matches", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer Analyzer.Flags.Set("matches", "false")
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "handlers")
+}
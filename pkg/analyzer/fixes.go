@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+// suggestedFixes returns the fixes for the mismatches of diag that can be
+// fixed mechanically, which for now is adding or removing a pointer. fd is
+// the function that was diagnosed.
+func suggestedFixes(fd *ast.FuncDecl, diag detect.Diagnostic) []analysis.SuggestedFix {
+	if fd == nil {
+		return nil
+	}
+	var fixes []analysis.SuggestedFix
+	for _, m := range diag.Mismatches {
+		if !onlyPointerDiffers(m) {
+			continue
+		}
+		fields := fd.Type.Params
+		what := "argument"
+		if m.Result {
+			fields = fd.Type.Results
+			what = "result"
+		}
+		field := fieldAt(fields, m.Index)
+		// Changing a field like "a, b T" would change all of them.
+		if field == nil || len(field.Names) > 1 {
+			continue
+		}
+		var edit analysis.TextEdit
+		if m.Want.Pointer {
+			edit = analysis.TextEdit{Pos: field.Type.Pos(), End: field.Type.Pos(), NewText: []byte("*")}
+		} else if star, ok := field.Type.(*ast.StarExpr); ok {
+			edit = analysis.TextEdit{Pos: star.Star, End: star.X.Pos()}
+		} else {
+			continue
+		}
+		fixes = append(fixes, analysis.SuggestedFix{
+			Message:   fmt.Sprintf("Change %s %d to %s", what, m.Index+1, m.Want.String()),
+			TextEdits: []analysis.TextEdit{edit},
+		})
+	}
+	return fixes
+}
+
+// onlyPointerDiffers reports whether the argument would match if it was (or
+// wasn't) a pointer.
+func onlyPointerDiffers(m detect.Mismatch) bool {
+	got, want := m.Got, m.Want
+	if got.Pointer == want.Pointer {
+		return false
+	}
+	got.Pointer, got.ParamName = want.Pointer, want.ParamName
+	return reflect.DeepEqual(got, want)
+}
+
+// fieldAt returns the field declaring the argument at index, taking fields
+// that declare multiple arguments into account.
+func fieldAt(fields *ast.FieldList, index int) *ast.Field {
+	if fields == nil {
+		return nil
+	}
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		if index < n {
+			return field
+		}
+		index -= n
+	}
+	return nil
+}
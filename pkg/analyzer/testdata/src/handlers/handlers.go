@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+func Handle(w http.ResponseWriter, r *http.Request) { // want `function Handle matches func\(http.ResponseWriter, \*http.Request\)` `Handle is one of 2 matches in the package, expecting 1`
+}
+
+func Serve(w http.ResponseWriter, r *http.Request) { // want `function Serve matches` `Serve is one of 2 matches`
+}
+
+func Value(w http.ResponseWriter, r http.Request) { // want "Value does not match func\\(http.ResponseWriter, \\*http.Request\\): arg 2 is `http.Request`, expected `\\*http.Request`"
+}
+
+func receive(w http.ResponseWriter, r *http.Request) { // want "receive does not match .*: function `receive` is unexported"
+}
@@ -0,0 +1,11 @@
+package single
+
+import (
+	"net/http"
+)
+
+func Handle(w http.ResponseWriter, r *http.Request) {
+}
+
+func helper(s string) {
+}
@@ -0,0 +1,8 @@
+package single
+
+import (
+	"net/http"
+)
+
+func HandleForTest(w http.ResponseWriter, r *http.Request) {
+}
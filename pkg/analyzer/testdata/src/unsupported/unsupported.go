@@ -0,0 +1,11 @@
+package unsupported
+
+import (
+	"net/http"
+)
+
+func Handle(w http.ResponseWriter, r *http.Request) {
+}
+
+func Pipe(in chan chan int) { // want "Pipe uses unsupported type `chan chan int` and can not be checked"
+}
@@ -0,0 +1,181 @@
+package detect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+)
+
+// SourceKind tells what a config was loaded from.
+type SourceKind string
+
+const (
+	SourceFile SourceKind = "file"
+	SourceURL  SourceKind = "url"
+	// SourceEmbedded is a config (or signatures) given by the program
+	// itself, for example as a string.
+	SourceEmbedded SourceKind = "embedded"
+)
+
+// Source is where a config was loaded from, so that errors and the tools
+// using the Detector can tell which signatures they're about.
+type Source struct {
+	Kind SourceKind
+	// Location is the file name or URL, empty for embedded configs.
+	Location string
+}
+
+func (s Source) String() string {
+	if s.Location == "" {
+		return string(s.Kind)
+	}
+	return string(s.Kind) + " " + s.Location
+}
+
+// wrap adds the source to err, unless it's the zero Source.
+func (s Source) wrap(err error) error {
+	if s == (Source{}) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", s, err)
+}
+
+// Config is a validated signatures config.
+type Config struct {
+	FunctionSignatures
+	// Format is the format the config was parsed as.
+	Format Format
+	Source Source
+}
+
+// ParseFormat returns the Format called s, which is one of json, yaml or
+// toml.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case JSON, YAML, TOML:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected one of %s, %s or %s", s, JSON, YAML, TOML)
+}
+
+// ParseConfig parses the config written in format and checks it against the
+// Schema. If format is empty it's detected from the content, see Validate.
+// Errors are prefixed with the source.
+func ParseConfig(config string, format Format, source Source) (*Config, error) {
+	if format == "" {
+		format = detectFormat(config)
+	}
+	if err := validate(config, format); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			verr.Source = source
+			return nil, verr
+		}
+		return nil, source.wrap(err)
+	}
+	c := &Config{Format: format, Source: source}
+	var err error
+	if format == TOML {
+		_, err = toml.Decode(config, &c.FunctionSignatures)
+	} else {
+		err = yaml.Unmarshal([]byte(config), &c.FunctionSignatures)
+	}
+	if err != nil {
+		return nil, source.wrap(err)
+	}
+	if err := c.parseSignatures(); err != nil {
+		return nil, source.wrap(err)
+	}
+	return c, nil
+}
+
+// LoadConfigFile reads the config from a file. If format is empty it's
+// inferred from the extension (.json, .yaml, .yml or .toml) and failing that
+// from the content.
+func LoadConfigFile(fileName string, format Format) (*Config, error) {
+	in, err := readFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = formatFromExtension(fileName)
+	}
+	return ParseConfig(in, format, Source{Kind: SourceFile, Location: fileName})
+}
+
+// LoadConfigURL fetches the config from a URL. Responses with a status other
+// than 2xx are errors, and so are the ones with a Content-Type that is not a
+// config, like the text/html of error pages. If format is empty it's
+// inferred from the Content-Type, failing that from the extension of the
+// path of the URL and then from the content.
+func LoadConfigURL(u string, format Format) (*Config, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	contentFormat, err := formatFromContentType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = contentFormat
+	}
+	if parsed, err := url.Parse(u); format == "" && err == nil {
+		format = formatFromExtension(parsed.Path)
+	}
+	return ParseConfig(string(body), format, Source{Kind: SourceURL, Location: u})
+}
+
+// formatFromExtension returns the format of the file name, or "" if the
+// extension is not one of the formats.
+func formatFromExtension(name string) Format {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	}
+	return ""
+}
+
+// formatFromContentType returns the format of the content type, or "" for
+// the generic types that say nothing about the format, like text/plain
+// which is what raw files are usually served as.
+func formatFromContentType(contentType string) (Format, error) {
+	if contentType == "" {
+		return "", nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	switch {
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		return JSON, nil
+	case mediaType == "application/yaml" || mediaType == "application/x-yaml" || mediaType == "text/yaml" || mediaType == "text/x-yaml" || strings.HasSuffix(mediaType, "+yaml"):
+		return YAML, nil
+	case mediaType == "application/toml" || mediaType == "text/toml" || mediaType == "text/x-toml":
+		return TOML, nil
+	case mediaType == "text/plain" || mediaType == "application/octet-stream":
+		return "", nil
+	}
+	return "", fmt.Errorf("unexpected content type %s, expected a json, yaml or toml config", mediaType)
+}
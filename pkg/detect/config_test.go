@@ -0,0 +1,119 @@
+package detect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigURL(t *testing.T) {
+	files := map[string]string{}
+	for _, file := range []string{signatureFileJSON, signatureFileYAML, signatureFileTOML} {
+		config, err := readFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s", file, err)
+		}
+		files[filepath.Ext(file)] = config
+	}
+	mux := http.NewServeMux()
+	serve := func(pattern, contentType, body string, status int) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		})
+	}
+	serve("/json", "application/json; charset=utf-8", files[".json"], http.StatusOK)
+	serve("/yaml", "application/yaml", files[".yaml"], http.StatusOK)
+	serve("/signatures.toml", "text/plain; charset=utf-8", files[".toml"], http.StatusOK)
+	serve("/plain", "text/plain", files[".yaml"], http.StatusOK)
+	serve("/mislabeled", "application/json", files[".yaml"], http.StatusOK)
+	serve("/missing", "text/plain", "404: Not Found", http.StatusNotFound)
+	serve("/html", "text/html", "<html><body>Sign in</body></html>", http.StatusOK)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		format Format
+		want   Format
+		err    string
+	}{
+		{path: "/json", want: JSON},
+		{path: "/yaml", want: YAML},
+		{path: "/signatures.toml", want: TOML},
+		{path: "/plain", want: YAML},
+		{path: "/mislabeled", err: "invalid json config"},
+		{path: "/mislabeled", format: YAML, want: YAML},
+		{path: "/missing", err: "404 Not Found"},
+		{path: "/html", err: "unexpected content type text/html"},
+	}
+	for _, test := range tests {
+		t.Run(test.path+"/"+string(test.format), func(t *testing.T) {
+			u := server.URL + test.path
+			c, err := LoadConfigURL(u, test.format)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) || !strings.Contains(err.Error(), u) {
+					t.Fatalf("Wanted error %q for %s, got %v", test.err, u, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load %s: %s", u, err)
+			}
+			if c.Format != test.want {
+				t.Errorf("Wanted format %s, got %s", test.want, c.Format)
+			}
+			if want := (Source{Kind: SourceURL, Location: u}); c.Source != want {
+				t.Errorf("Wanted source %s, got %s", want, c.Source)
+			}
+			if d := NewDetectorFromConfig(c); d.Source() != c.Source {
+				t.Errorf("Wanted the detector to have source %s, got %s", c.Source, d.Source())
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlConfig, err := readFile(signatureFileYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"signatures.txt":  yamlConfig,
+		"signatures.json": yamlConfig,
+	})
+
+	c, err := LoadConfigFile(filepath.Join(dir, "signatures.txt"), "")
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+	if c.Format != YAML || c.Source.Kind != SourceFile {
+		t.Errorf("Wanted a yaml config from a file, got %s from %s", c.Format, c.Source)
+	}
+
+	// The extension wins over the content, but not over the format.
+	file := filepath.Join(dir, "signatures.json")
+	if _, err := LoadConfigFile(file, ""); err == nil || !strings.HasPrefix(err.Error(), "file "+file+": invalid json config") {
+		t.Errorf("Wanted a json error for %s, got %v", file, err)
+	}
+	if _, err := LoadConfigFile(file, YAML); err != nil {
+		t.Errorf("Failed to load %s as yaml: %s", file, err)
+	}
+
+	if d := NewDetector(nil); d.Source().Kind != SourceEmbedded {
+		t.Errorf("Wanted embedded signatures, got %s", d.Source())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("TOML"); err != nil || f != TOML {
+		t.Errorf("Wanted toml, got %s, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Wanted an error for xml")
+	}
+}
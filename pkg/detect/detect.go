@@ -5,15 +5,13 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"io/ioutil"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
-
-	"github.com/BurntSushi/toml"
-	"github.com/ghodss/yaml"
 )
 
 type Function struct {
@@ -29,20 +27,58 @@ const (
 	Both    ChanDir = "BOTH"
 )
 
+// Wildcard can be used as the Name of a FunctionArg in a signature to match
+// any type. Setting Pointer or Channel as well restricts it to pointers or
+// channels (of any type) and setting ImportPath restricts it to any type
+// declared in that package. Since * already denotes pointers, wildcards are
+// printed as _ by String.
+const Wildcard = "*"
+
 // FunctionArg describes a single argument (input or output) for a given variable
 // For example a function from "net/http" that conforms to the Handler function, that looks
 // like this "func(http.ResponseWriter, *http.Request)" would have two arguments like so:
 // FunctionArg{ImportPath: "net/http", Name: "ResponseWriter"}
 // FunctionArg{ImportPath: "net/http", Name: "Request", Pointer: true}
+//
+// Slices, arrays, maps and functions describe their element types with
+// nested FunctionArgs. For example "[]byte" would be:
+// FunctionArg{Slice: true, Elem: &FunctionArg{Name: "byte"}}
+// and "map[string]*http.Request" would be:
+// FunctionArg{Key: &FunctionArg{Name: "string"}, Elem: &FunctionArg{ImportPath: "net/http", Name: "Request", Pointer: true}}
+// Channel and Pointer apply to the whole type, in that order, so "chan *[]byte"
+// is a Channel with Pointer and Slice set.
+//
+// The last input argument can be marked Variadic, in which case it describes
+// the type of the individual arguments, so "...string" would be:
+// FunctionArg{Name: "string", Variadic: true}
 type FunctionArg struct {
-	ImportPath string  `json:"importPath,omitempty"`
-	Name       string  `json:"name"`
-	Pointer    bool    `json:"pointer,omitempty"`
-	Channel    ChanDir `json:"channel,omitempty"`
+	ImportPath string  `json:"importPath,omitempty" toml:"importPath,omitempty"`
+	Name       string  `json:"name,omitempty" toml:"name,omitempty"`
+	Pointer    bool    `json:"pointer,omitempty" toml:"pointer,omitempty"`
+	Channel    ChanDir `json:"channel,omitempty" toml:"channel,omitempty"`
+	// Slice is set for slices, Elem is the type of the elements.
+	Slice bool `json:"slice,omitempty" toml:"slice,omitempty"`
+	// ArrayLen is set for arrays, Elem is the type of the elements.
+	ArrayLen int `json:"arrayLen,omitempty" toml:"arrayLen,omitzero"`
+	// Key is set for maps, Elem is the type of the values.
+	Key  *FunctionArg `json:"key,omitempty" toml:"key,omitempty"`
+	Elem *FunctionArg `json:"elem,omitempty" toml:"elem,omitempty"`
+	// Func is set for function types.
+	Func *FunctionSignature `json:"func,omitempty" toml:"func,omitempty"`
+	// Variadic is set for the last argument of a variadic function.
+	Variadic bool `json:"variadic,omitempty" toml:"variadic,omitempty"`
+	// ParamName is the name of the parameter (or result). It's always set
+	// for the arguments of a function found in Go source, unless they are
+	// unnamed. In a signature it's optional, but if set the function must
+	// use the same name.
+	ParamName string `json:"paramName,omitempty" toml:"paramName,omitempty"`
 }
 
 func (fa *FunctionArg) String() string {
 	ret := ""
+	if fa.Variadic {
+		ret += "..."
+	}
 
 	// If it's a channel, print it out.
 	switch fa.Channel {
@@ -57,6 +93,18 @@ func (fa *FunctionArg) String() string {
 	if fa.Pointer {
 		ret += "*"
 	}
+
+	switch {
+	case fa.Slice:
+		return ret + "[]" + fa.Elem.String()
+	case fa.ArrayLen > 0:
+		return ret + "[" + strconv.Itoa(fa.ArrayLen) + "]" + fa.Elem.String()
+	case fa.Key != nil:
+		return ret + "map[" + fa.Key.String() + "]" + fa.Elem.String()
+	case fa.Func != nil:
+		return ret + fa.Func.String()
+	}
+
 	// If there's a slash In the path, pull Out the last part of the path, otherwise use full
 	// for things like "context", "fmt", etc.
 	pkg := fa.ImportPath
@@ -64,104 +112,391 @@ func (fa *FunctionArg) String() string {
 		pathPieces := strings.Split(fa.ImportPath, "/")
 		pkg = pathPieces[len(pathPieces)-1]
 	}
+	name := fa.Name
+	if name == Wildcard {
+		name = "_"
+	}
 	if pkg != "" {
-		ret += pkg + "." + fa.Name
+		ret += pkg + "." + name
 	} else {
-		ret += fa.Name
+		ret += name
 	}
 	return ret
 }
 
+// matches reports whether the actual argument matches fa, which may contain
+// wildcards. The concrete types matched by the wildcards are appended to
+// wildcards, unless it's nil.
+func (fa *FunctionArg) matches(actual *FunctionArg, wildcards *[]FunctionArg) bool {
+	if fa == nil || actual == nil {
+		return fa == actual
+	}
+	if fa.Name == Wildcard {
+		if fa.Variadic != actual.Variadic ||
+			fa.ParamName != "" && fa.ParamName != actual.ParamName ||
+			fa.Channel != "" && fa.Channel != actual.Channel ||
+			fa.Pointer && !actual.Pointer ||
+			fa.ImportPath != "" && fa.ImportPath != actual.ImportPath {
+			return false
+		}
+		if wildcards != nil {
+			*wildcards = append(*wildcards, *actual)
+		}
+		return true
+	}
+	if fa.ImportPath != actual.ImportPath || fa.Name != actual.Name || fa.Pointer != actual.Pointer ||
+		fa.Channel != actual.Channel || fa.Slice != actual.Slice || fa.ArrayLen != actual.ArrayLen ||
+		fa.Variadic != actual.Variadic || fa.ParamName != "" && fa.ParamName != actual.ParamName {
+		return false
+	}
+	if !fa.Key.matches(actual.Key, wildcards) || !fa.Elem.matches(actual.Elem, wildcards) {
+		return false
+	}
+	if fa.Func == nil || actual.Func == nil {
+		return fa.Func == actual.Func
+	}
+	return fa.Func.matches(actual.Func, wildcards)
+}
+
 type FunctionSignature struct {
-	In  []FunctionArg `json:"in,omitempty"`
-	Out []FunctionArg `json:"out,omitempty"`
+	SignatureInfo
+	// Signature is the signature in Go syntax, see ParseSignature. If it's
+	// set, the TypeParams, In and Out are parsed from it when the config is
+	// read.
+	Signature string `json:"signature,omitempty" toml:"signature,omitempty"`
+	// Receiver controls whether methods match the signature. By default both
+	// functions and methods do.
+	Receiver ReceiverMode `json:"receiver,omitempty" toml:"receiver,omitempty"`
+	// TypeParams are set for generic functions. Arguments refer to them by
+	// their Name, without an ImportPath.
+	TypeParams []TypeParam   `json:"typeParams,omitempty" toml:"typeParams,omitempty"`
+	In         []FunctionArg `json:"in,omitempty" toml:"in,omitempty"`
+	Out        []FunctionArg `json:"out,omitempty" toml:"out,omitempty"`
 }
 
 type FunctionSignatures struct {
-	FunctionSignatures []FunctionSignature `json:"functionSignatures"`
+	FunctionSignatures []FunctionSignature `json:"functionSignatures" toml:"functionSignatures"`
+	// Interfaces are matched against the named types, see WithInterfaces.
+	Interfaces []InterfaceSignature `json:"interfaces,omitempty" toml:"interfaces,omitempty"`
+	// Imports maps the package names used in the Signature strings to
+	// their import paths.
+	Imports map[string]string `json:"imports,omitempty" toml:"imports,omitempty"`
+}
+
+// DefaultSignatures are the signatures the tools use unless they're given
+// others: the one of an HTTP handler, func(http.ResponseWriter, *http.Request).
+var DefaultSignatures = []FunctionSignature{
+	{
+		SignatureInfo: SignatureInfo{
+			ID:       "http",
+			Metadata: map[string]string{"protocol": "http"},
+		},
+		In: []FunctionArg{
+			{ImportPath: "net/http", Name: "ResponseWriter"},
+			{ImportPath: "net/http", Name: "Request", Pointer: true},
+		}}}
+
+// matches reports whether the arguments of the actual signature match the
+// ones of fs, which may contain wildcards. The concrete types matched by the
+// wildcards are appended to wildcards, unless it's nil.
+func (fs *FunctionSignature) matches(actual *FunctionSignature, wildcards *[]FunctionArg) bool {
+	if len(fs.In) != len(actual.In) || len(fs.Out) != len(actual.Out) || len(fs.TypeParams) != len(actual.TypeParams) {
+		return false
+	}
+	if len(fs.TypeParams) > 0 {
+		// Type parameters are matched by position, whatever they are named.
+		// So refer to them with the names used by the actual function.
+		names := make(map[string]string, len(fs.TypeParams))
+		for i := range fs.TypeParams {
+			if !fs.TypeParams[i].Constraint.matches(&actual.TypeParams[i].Constraint, nil) {
+				return false
+			}
+			names[fs.TypeParams[i].Name] = actual.TypeParams[i].Name
+		}
+		renamed := renameTypeParams(*fs, names)
+		fs = &renamed
+	}
+	for i := range fs.In {
+		if !fs.In[i].matches(&actual.In[i], wildcards) {
+			return false
+		}
+	}
+	for i := range fs.Out {
+		if !fs.Out[i].matches(&actual.Out[i], wildcards) {
+			return false
+		}
+	}
+	return true
+}
+
+// variadicAsSlice returns a copy of fs where a variadic "...T" argument is
+// replaced with "[]T".
+func (fs *FunctionSignature) variadicAsSlice() FunctionSignature {
+	ret := *fs
+	if len(fs.In) == 0 || !fs.In[len(fs.In)-1].Variadic {
+		return ret
+	}
+	elem := fs.In[len(fs.In)-1]
+	elem.Variadic = false
+	slice := FunctionArg{Slice: true, Elem: &elem, ParamName: elem.ParamName}
+	elem.ParamName = ""
+	ret.In = append(append([]FunctionArg(nil), fs.In[:len(fs.In)-1]...), slice)
+	return ret
 }
 
 func (fs *FunctionSignature) String() string {
-	s := "func("
-	for i, in := range fs.In {
-		s += in.String()
-		if i != len(fs.In)-1 {
-			s += ", "
+	s := "func"
+	if len(fs.TypeParams) > 0 {
+		s += "["
+		for i, tp := range fs.TypeParams {
+			s += tp.String()
+			if i != len(fs.TypeParams)-1 {
+				s += ", "
+			}
 		}
+		s += "]"
 	}
-	s += ")"
+	s += "(" + argList(fs.In) + ")"
 	if len(fs.Out) > 0 {
-		if len(fs.Out) > 1 {
-			s += " ("
+		// Just a single unnamed argument, no parens, just a space please.
+		if len(fs.Out) == 1 && fs.Out[0].ParamName == "" {
+			s += " " + fs.Out[0].String()
 		} else {
-			// Just a single argument, no parens, just a space please.
-			s += " "
+			s += " (" + argList(fs.Out) + ")"
 		}
-		for i, out := range fs.Out {
-			s += out.String()
-			if i != len(fs.Out)-1 {
-				s += ", "
+	}
+	return s
+}
+
+// argList returns the comma separated list of args. If any of them are named,
+// the ones that aren't are named _ so that it's still valid Go.
+func argList(args []FunctionArg) string {
+	named := false
+	for _, arg := range args {
+		if arg.ParamName != "" {
+			named = true
+		}
+	}
+	s := ""
+	for i, arg := range args {
+		if named {
+			if arg.ParamName != "" {
+				s += arg.ParamName + " "
+			} else {
+				s += "_ "
 			}
 		}
-		if len(fs.Out) > 1 {
-			s += ")"
+		s += arg.String()
+		if i != len(args)-1 {
+			s += ", "
 		}
 	}
 	return s
 }
 
 type FunctionDetails struct {
+	// Kind is KindFunction for functions and methods, KindVariable for
+	// variables holding a function, KindFactory for functions returning one
+	// or KindType for types that implement one of the interfaces. For types,
+	// Name is the name of the type, Signature is the interface and
+	// PointerReceiver is set if only a pointer to the type implements it.
+	Kind      Kind
 	Name      string
 	Package   string
 	Signature string
+	// Position is where the function is declared.
+	Position token.Position
+	// Wildcards are the concrete types of the function that matched the
+	// wildcard arguments of the signature, in order.
+	Wildcards []FunctionArg
+	// TypeParams are the type parameters of a generic function, as declared
+	// by the function.
+	TypeParams []TypeParam
+	// Params and Results are the arguments of the function as declared,
+	// including their names.
+	Params  []FunctionArg
+	Results []FunctionArg
+	// Receiver is the name of the receiver type if the function is a method.
+	Receiver string
+	// PointerReceiver is set if the method has a pointer receiver.
+	PointerReceiver bool
+	// Constructor is the name of a function that returns an instance of the
+	// Receiver type, if there is one. See findConstructors.
+	Constructor string
+	// ConstructorError is set if the Constructor also returns an error.
+	ConstructorError bool
+	// FactoryError is set if a factory also returns an error.
+	FactoryError bool
+	// Match is the signature that matched, for functions, variables and
+	// factories.
+	Match *FunctionSignature
+	// Interface is the interface that a type implements.
+	Interface *InterfaceSignature
 }
 
 type Detector struct {
 	sigs []FunctionSignature
+
+	// typeCheck controls whether argument types are resolved with go/types
+	// before falling back to the syntax based comparison.
+	typeCheck bool
+	checker   *typeChecker
+
+	// variadicAsSlice controls whether "...T" and "[]T" are considered the
+	// same.
+	variadicAsSlice bool
+
+	// interfaces are matched against the named types declared in the
+	// scanned source.
+	interfaces []InterfaceSignature
+
+	// factories controls whether functions returning a matching function
+	// are reported.
+	factories bool
+
+	// exportedOnly controls whether unexported functions, variables and
+	// factories are left out.
+	exportedOnly bool
+
+	// logger gets the details of the matching, which are mostly useful for
+	// debugging signatures. See WithLogger.
+	logger *slog.Logger
+
+	// source is where the signatures were loaded from.
+	source Source
+
+	// sigOrder and ifaceOrder are the indexes of the signatures and
+	// interfaces in the order they're tried, see SignatureInfo.Priority.
+	sigOrder   []int
+	ifaceOrder []int
 }
 
-func NewDetector(sigs []FunctionSignature) *Detector {
-	return &Detector{sigs: sigs}
+// Option configures optional behaviour of a Detector.
+type Option func(*Detector)
+
+// WithTypeChecking makes the Detector load the scanned source with go/types
+// and compare the resolved argument types against the signatures. This means
+// that type aliases, dot-imports and re-exported types resolve to the type
+// they actually refer to. Arguments whose types can not be resolved (for
+// example because an import can not be found) fall back to the syntax based
+// comparison.
+func WithTypeChecking() Option {
+	return func(d *Detector) {
+		d.typeCheck = true
+	}
 }
 
-func NewDetectorFromURL(u string) (*Detector, error) {
-	resp, err := http.Get(u)
-	if err != nil {
-		return nil, err
+// WithVariadicAsSlice makes the Detector treat a variadic "...T" argument
+// the same as a "[]T" argument. By default they are considered distinct.
+func WithVariadicAsSlice() Option {
+	return func(d *Detector) {
+		d.variadicAsSlice = true
+	}
+}
+
+// WithInterfaces makes the Detector also look for exported named types that
+// implement any of the given interfaces, which are reported as KindType.
+func WithInterfaces(ifaces ...InterfaceSignature) Option {
+	return func(d *Detector) {
+		d.interfaces = append(d.interfaces, ifaces...)
+	}
+}
+
+// WithFactories makes the Detector also report the functions that take no
+// arguments and return a function matching one of the signatures (and
+// optionally an error), as KindFactory. For example:
+// func NewHandler() http.HandlerFunc
+func WithFactories() Option {
+	return func(d *Detector) {
+		d.factories = true
+	}
+}
+
+// WithExportedOnly makes the Detector leave out the unexported functions,
+// variables and factories, which can not be used outside of the package.
+// An unexported function that would match is reported as a near miss
+// instead.
+func WithExportedOnly() Option {
+	return func(d *Detector) {
+		d.exportedOnly = true
+	}
+}
+
+// WithLogger makes the Detector log the signatures it tries, the matches and
+// the unsupported types it comes across to logger. By default nothing is
+// logged, which is also what a nil logger does.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Detector) {
+		d.logger = logger
+	}
+}
+
+func NewDetector(sigs []FunctionSignature, opts ...Option) *Detector {
+	// The checker is also used to resolve the signatures when the caller
+	// provides the type information, see ScanFiles.
+	d := &Detector{sigs: sigs, checker: newTypeChecker(), source: Source{Kind: SourceEmbedded}}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.logger == nil {
+		d.logger = slog.New(discardHandler{})
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	d.sigOrder = byPriority(len(d.sigs), func(i int) int { return d.sigs[i].Priority })
+	d.ifaceOrder = byPriority(len(d.interfaces), func(i int) int { return d.interfaces[i].Priority })
+	return d
+}
+
+// NewDetectorFromURL fetches the signatures from a URL, see LoadConfigURL.
+func NewDetectorFromURL(u string, opts ...Option) (*Detector, error) {
+	c, err := LoadConfigURL(u, "")
 	if err != nil {
 		return nil, err
 	}
-	return NewDetectorFromString(string(body))
+	return NewDetectorFromConfig(c, opts...), nil
 }
 
-func NewDetectorFromFile(fileName string) (*Detector, error) {
-	in, err := readFile(fileName)
+// NewDetectorFromFile reads the signatures from a file, see LoadConfigFile.
+func NewDetectorFromFile(fileName string, opts ...Option) (*Detector, error) {
+	c, err := LoadConfigFile(fileName, "")
 	if err != nil {
 		return nil, err
 	}
-	return NewDetectorFromString(in)
+	return NewDetectorFromConfig(c, opts...), nil
 }
 
-func NewDetectorFromString(config string) (*Detector, error) {
-	var fs FunctionSignatures
-	if err := yaml.Unmarshal([]byte(config), &fs); err == nil {
-		return &Detector{sigs: fs.FunctionSignatures}, err
-	}
-	// Ok, try to parse it as toml.
-	if _, err := toml.Decode(config, &fs); err != nil {
+// NewDetectorFromString reads the signatures from a config in JSON, YAML or
+// TOML, see ParseConfig.
+func NewDetectorFromString(config string, opts ...Option) (*Detector, error) {
+	c, err := ParseConfig(config, "", Source{Kind: SourceEmbedded})
+	if err != nil {
 		return nil, err
 	}
-	return &Detector{sigs: fs.FunctionSignatures}, nil
+	return NewDetectorFromConfig(c, opts...), nil
+}
+
+// NewDetectorFromConfig returns a Detector for the signatures and interfaces
+// of the config, which remembers the source of the config.
+func NewDetectorFromConfig(c *Config, opts ...Option) *Detector {
+	d := NewDetector(c.FunctionSignatures.FunctionSignatures, append(opts, WithInterfaces(c.Interfaces...))...)
+	d.source = c.Source
+	d.logger.Debug("Loaded signatures", "source", c.Source, "format", c.Format)
+	return d
 }
 
-func (d *Detector) Signatures() string{
+// Source returns where the signatures of the Detector were loaded from. It's
+// SourceEmbedded for the ones passed to NewDetector.
+func (d *Detector) Source() Source {
+	return d.source
+}
+
+func (d *Detector) Signatures() string {
 	ret := ""
 	for _, sig := range d.sigs {
 		ret += sig.String() + "\n"
 	}
+	for _, iface := range d.interfaces {
+		ret += iface.String() + "\n"
+	}
 	return ret
 }
 
@@ -201,122 +536,289 @@ func (d *Detector) CheckFile(f *Function) (*FunctionDetails, error) {
 	if err != nil {
 		return nil, err
 	}
+	return unique(found)
+}
+
+// AmbiguityError is returned when more than one function matches where only
+// a single one is expected.
+type AmbiguityError struct {
+	Matches []FunctionDetails
+}
+
+func (e *AmbiguityError) Error() string {
+	names := make([]string, 0, len(e.Matches))
+	for _, f := range e.Matches {
+		names = append(names, f.Name)
+	}
+	return fmt.Sprintf("Found %d matching signatures, expecting 1: %s", len(e.Matches), names)
+}
+
+// unique returns the only function in found, nil if there are none or the
+// first one along with an AmbiguityError if there are more.
+func unique(found []FunctionDetails) (*FunctionDetails, error) {
 	if len(found) > 1 {
-		names := make([]string, 0, len(found))
-		for _, f := range found {
-			names = append(names, f.Name)
-		}
-		return &found[0], fmt.Errorf("Found %d matching signatures, expecting 1: %s", len(found), names)
+		return &found[0], &AmbiguityError{Matches: found}
 	}
 	if len(found) == 0 {
 		return nil, nil
 	}
-	return &found[0], err
+	return &found[0], nil
 }
 
 func (d *Detector) AllFromFile(f *Function) ([]FunctionDetails, error) {
-	var retval []FunctionDetails
 	// file set
 	fset := token.NewFileSet()
 	astFile, err := parser.ParseFile(fset, f.File, f.Source, 0)
 	if err != nil {
-		return retval, err
+		return nil, err
+	}
+	// There's no way of knowing the import path of a lone file, so just use
+	// the package name for it.
+	found, _, _ := d.detect(fset, astFile.Name.Name, filepath.Dir(f.File), []*ast.File{astFile}, nil)
+	return found, nil
+}
+
+// detect returns all the functions in files that match one of the
+// signatures and all the types that implement one of the interfaces, in the
+// order they are declared, along with the near misses and the functions
+// using unsupported types, see UnsupportedTypeError. All the files must
+// belong to the same package, which has the import path path and lives in
+// the directory dir. info is the type information of the package or nil, in
+// which case the files are type checked if requested.
+func (d *Detector) detect(fset *token.FileSet, path, dir string, files []*ast.File, info *types.Info) ([]FunctionDetails, []Diagnostic, []error) {
+	// If requested, resolve the types so that checkFunction can use them.
+	if info == nil && d.typeCheck {
+		info = d.checker.check(fset, path, files)
+	}
+	var sigs []FunctionSignature
+	ifaces := d.interfaces
+	if info != nil {
+		sigs = d.checker.resolveSignatures(d.sigs, dir)
+		ifaces = d.checker.resolveInterfaces(d.interfaces, dir)
+	}
+
+	// Methods and func types can be declared in any of the files, so collect
+	// them all before looking at the declarations using them.
+	resolvers := make([]*argResolver, 0, len(files))
+	methods := make(map[string][]method)
+	declared := make(map[string]FunctionSignature)
+	for _, astFile := range files {
+		resolver := &argResolver{imports: fileImports(astFile), info: info, sigs: sigs, funcTypes: declared}
+		resolvers = append(resolvers, resolver)
+		funcTypes(resolver, astFile, declared)
+		if len(ifaces) > 0 {
+			fileMethods(resolver, astFile, methods)
+		}
+	}
+
+	var retval []FunctionDetails
+	var diags []Diagnostic
+	var errs []error
+	for i, astFile := range files {
+		found, fileDiags, fileErrs := d.detectFile(fset, astFile, resolvers[i], ifaces, methods)
+		retval = append(retval, found...)
+		diags = append(diags, fileDiags...)
+		errs = append(errs, fileErrs...)
+	}
+
+	// Methods can only be called with an instance of the receiver, so see
+	// if there's a way to create one. It can be in any of the files.
+	constructors := findConstructors(files)
+	for i := range retval {
+		var c constructor
+		switch {
+		case retval[i].Kind == KindType:
+			c = constructors[retval[i].Name]
+		case retval[i].Receiver != "":
+			c = constructors[retval[i].Receiver]
+		}
+		retval[i].Constructor, retval[i].ConstructorError = c.name, c.err
 	}
+	return retval, diags, errs
+}
 
-	// imports keeps track of which files that we care about are imported as which
-	// local imports. For example if you import:
-	// 	nethttp "net/http"
-	// localImports["nethttp"] -> "net/http"
+// fileImports returns which packages are imported as which local names in
+// astFile. For example if you import:
+//
+//	nethttp "net/http"
+//
+// imports["nethttp"] -> "net/http"
+func fileImports(astFile *ast.File) map[string]string {
 	localImports := make(map[string]string)
+	for _, i := range astFile.Imports {
+		// We need to unquote the path first since it's quoted. The parser
+		// already made sure that it's a valid string.
+		impPath, err := strconv.Unquote(i.Path.Value)
+		if err != nil {
+			continue
+		}
 
-	// main inspection
-	ast.Inspect(astFile, func(n ast.Node) bool {
-		switch fn := n.(type) {
-		// Create the mapping of localimports to the full import paths
-		case *ast.File:
-			for _, i := range fn.Imports {
-				// We need to unquote the path first since it's quoted
-				impPath, err := strconv.Unquote(i.Path.Value)
-				if err != nil {
-					fmt.Printf("failed to unquote import path %q : %s\n", i.Path.Value, err)
-					return false
-				}
+		if i.Name != nil {
+			// There's a local import path, use that
+			localImports[i.Name.String()] = impPath
+		} else {
+			// There isn't a local import defined, so use the last part
+			// of the import path
+			pathPieces := strings.Split(impPath, "/")
+			localImports[pathPieces[len(pathPieces)-1]] = impPath
+		}
+	}
+	return localImports
+}
+
+// usable reports whether the function or variable name can be matched, see
+// WithExportedOnly.
+func (d *Detector) usable(name *ast.Ident) bool {
+	return !d.exportedOnly || name.IsExported()
+}
 
-				if i.Name != nil {
-					// There's a local import path, use that
-					localImports[i.Name.String()] = impPath
-				} else {
-					// There isn't a local import defined, so use the last part
-					// of the import path
-					pathPieces := strings.Split(impPath, "/")
-					localImports[pathPieces[len(pathPieces)-1]] = impPath
+// detectFile returns the functions in a single file that match one of the
+// signatures (or hold or return one) and the types that implement one of
+// ifaces, given the methods declared in the whole package. Functions that
+// don't match are diagnosed, see diagnose, or reported if they use
+// unsupported types.
+func (d *Detector) detectFile(fset *token.FileSet, astFile *ast.File, resolver *argResolver, ifaces []InterfaceSignature, methods map[string][]method) ([]FunctionDetails, []Diagnostic, []error) {
+	var retval []FunctionDetails
+	var diags []Diagnostic
+	var errs []error
+	for _, decl := range astFile.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			// Functions using unsupported types are not matched at all,
+			// they could only match by accident, for example a wildcard.
+			var details *FunctionDetails
+			var unsupported []error
+			usable := d.usable(decl.Name)
+			if usable {
+				unsupported = unsupportedTypes(fset, resolver, decl.Name.Name, decl.Type)
+			}
+			if usable && len(unsupported) == 0 {
+				details = d.checkFunction(resolver, decl.Type, decl.Recv != nil)
+				if details == nil && d.factories {
+					details, unsupported = d.checkFactory(fset, resolver, decl)
 				}
 			}
-			for _, decl := range fn.Decls {
-				if f, ok := decl.(*ast.FuncDecl); ok {
-					if f.Recv != nil {
-						fmt.Println("Found receiver ", f.Recv)
+			if details != nil {
+				details.Name = decl.Name.Name
+				details.Position = fset.Position(decl.Name.Pos())
+				details.Receiver, details.PointerReceiver = receiverType(decl.Recv)
+				retval = append(retval, *details)
+				continue
+			}
+			// The differences to a function using unsupported types don't
+			// say much, so report the types instead.
+			for _, err := range unsupported {
+				d.logger.Warn("Skipping unsupported type", "error", err)
+				errs = append(errs, err)
+			}
+			if len(unsupported) > 0 {
+				continue
+			}
+			if diag := d.diagnose(fset, resolver, decl); diag != nil {
+				diags = append(diags, *diag)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					if decl.Tok == token.VAR {
+						found, unsupported := d.checkVariables(fset, resolver, spec)
+						retval = append(retval, found...)
+						errs = append(errs, unsupported...)
 					}
-					if sig := d.checkFunction(localImports, f.Type); sig != "" {
-						retval = append(retval, FunctionDetails{Name: f.Name.Name, Signature: sig})
+				case *ast.TypeSpec:
+					if details := d.checkType(fset, spec, ifaces, methods); details != nil {
+						retval = append(retval, *details)
 					}
 				}
 			}
 		}
-		return true
-	})
-	return retval, nil
+	}
+	return retval, diags, errs
 }
 
-// checkFunction takes a function signature and returns a friendly (string)
-// representation of the supported function or "" if the function signature
-// is not supported.
-// For example
-// func Receive(http.ResponseWriter, *http.Request) {
-// would return:
-// func(http.ResponseWriter, *http.Request)
-func (d *Detector) checkFunction(c map[string]string, f *ast.FuncType) string {
+// signature returns the FunctionSignature of the function type f.
+func (r *argResolver) signature(f *ast.FuncType) FunctionSignature {
 	fs := FunctionSignature{}
-	if f == nil {
-		return ""
+	if f.TypeParams != nil {
+		for _, tp := range f.TypeParams.List {
+			constraint := r.resolve(tp.Type)
+			for _, name := range tp.Names {
+				fs.TypeParams = append(fs.TypeParams, TypeParam{Name: name.Name, Constraint: constraint})
+			}
+		}
 	}
 	if f.Params != nil {
 		for _, p := range f.Params.List {
-			t := typeToFunctionArg(c, p.Type)
-			fs.In = append(fs.In, t)
+			t := r.resolve(p.Type)
+			fs.In = append(fs.In, expandNames(p, t)...)
 		}
 	}
 	if f.Results != nil {
-		for _, r := range f.Results.List {
-			t := typeToFunctionArg(c, r.Type)
-			fs.Out = append(fs.Out, t)
+		for _, res := range f.Results.List {
+			t := r.resolve(res.Type)
+			fs.Out = append(fs.Out, expandNames(res, t)...)
 		}
 	}
+	return fs
+}
 
-	for _, v := range d.sigs {
-		sig := v.String()
-		fmt.Printf("Checking function signature: %q\n", sig)
-		if len(fs.In) == len(v.In) && len(fs.Out) == len(v.Out) {
-			match := true
-			for i := range fs.In {
-				if fs.In[i] != v.In[i] {
-					match = false
-					continue
-				}
-			}
-			for i := range fs.Out {
-				if fs.Out[i] != v.Out[i] {
-					match = false
-					continue
-				}
-			}
-			if match {
-				fmt.Printf("Found matching signature: %q\n", sig)
-				return sig
-			}
+// signatureMatches reports whether actual matches the signature sig and
+// returns the concrete types of the wildcards if it does.
+func (d *Detector) signatureMatches(sig, actual FunctionSignature) ([]FunctionArg, bool) {
+	if d.variadicAsSlice {
+		sig = sig.variadicAsSlice()
+		actual = actual.variadicAsSlice()
+	}
+	var wildcards []FunctionArg
+	if !sig.matches(&actual, &wildcards) {
+		return nil, false
+	}
+	return wildcards, true
+}
+
+// checkFunction takes a function signature and returns the details of the
+// matching signature, with a friendly (string) representation of it, or nil
+// if the function signature is not supported. recv tells whether the function
+// is a method. It's up to the caller to fill in the details about the
+// function itself.
+// For example
+// func Receive(http.ResponseWriter, *http.Request) {
+// would return the Signature:
+// func(http.ResponseWriter, *http.Request)
+func (d *Detector) checkFunction(resolver *argResolver, f *ast.FuncType, recv bool) *FunctionDetails {
+	if f == nil {
+		return nil
+	}
+	return d.checkSignature(resolver, resolver.signature(f), recv)
+}
+
+// checkSignature returns the details of the signature with the highest
+// priority that fs matches or nil if there is none.
+func (d *Detector) checkSignature(resolver *argResolver, fs FunctionSignature, recv bool) *FunctionDetails {
+	details := &FunctionDetails{Kind: KindFunction, TypeParams: fs.TypeParams, Params: fs.In, Results: fs.Out}
+
+	sigs := d.sigs
+	if resolver.sigs != nil {
+		sigs = resolver.sigs
+	}
+	for _, i := range d.sigOrder {
+		v := sigs[i]
+		sig := d.sigs[i].String()
+		d.logger.Debug("Checking function signature", "signature", sig)
+		if v.Receiver == ReceiverRequired && !recv || v.Receiver == ReceiverForbidden && recv {
+			continue
+		}
+		if wildcards, ok := d.signatureMatches(v, fs); ok {
+			d.logger.Debug("Found matching signature", "signature", sig)
+			details.Signature = sig
+			details.Wildcards = wildcards
+			match := d.sigs[i]
+			details.Match = &match
+			return details
 		}
 	}
-	return ""
+	return nil
 }
 
 // typeToFunctionArg will take import paths and an expression and maps it to
@@ -325,11 +827,13 @@ func typeToFunctionArg(c map[string]string, e ast.Expr) FunctionArg {
 	switch e := e.(type) {
 	// Check if pointer to Event
 	case *ast.StarExpr:
-		if s, ok := e.X.(*ast.SelectorExpr); ok {
-			if im, ok := s.X.(*ast.Ident); ok {
-				return FunctionArg{ImportPath: c[im.Name], Name: s.Sel.String(), Pointer: true}
-			}
+		dataType := typeToFunctionArg(c, e.X)
+		// Pointers to pointers or channels can not be expressed.
+		if dataType.Pointer || dataType.Channel != "" {
+			return FunctionArg{}
 		}
+		dataType.Pointer = true
+		return dataType
 	case *ast.SelectorExpr:
 		if im, ok := e.X.(*ast.Ident); ok {
 			return FunctionArg{ImportPath: c[im.Name], Name: e.Sel.String()}
@@ -338,7 +842,11 @@ func typeToFunctionArg(c map[string]string, e ast.Expr) FunctionArg {
 		// Built In... Or something else?
 		return FunctionArg{Name: e.Name}
 	case *ast.ChanType:
-		dataType := starOrSelect(c, e.Value)
+		dataType := typeToFunctionArg(c, e.Value)
+		// Channels of channels can not be expressed.
+		if dataType.Channel != "" {
+			return FunctionArg{}
+		}
 		switch e.Dir {
 		case ast.RECV:
 			dataType.Channel = Receive
@@ -348,25 +856,66 @@ func typeToFunctionArg(c map[string]string, e ast.Expr) FunctionArg {
 			dataType.Channel = Both
 		}
 		return dataType
+	case *ast.ArrayType:
+		elem := typeToFunctionArg(c, e.Elt)
+		if e.Len == nil {
+			return FunctionArg{Slice: true, Elem: &elem}
+		}
+		// Only literal lengths are supported, constants need type checking.
+		if l, ok := e.Len.(*ast.BasicLit); ok && l.Kind == token.INT {
+			if n, err := strconv.Atoi(l.Value); err == nil && n > 0 {
+				return FunctionArg{ArrayLen: n, Elem: &elem}
+			}
+		}
+	case *ast.MapType:
+		key := typeToFunctionArg(c, e.Key)
+		elem := typeToFunctionArg(c, e.Value)
+		return FunctionArg{Key: &key, Elem: &elem}
+	case *ast.FuncType:
+		sig := funcTypeToSignature(c, e)
+		return FunctionArg{Func: &sig}
+	case *ast.Ellipsis:
+		dataType := typeToFunctionArg(c, e.Elt)
+		dataType.Variadic = true
+		return dataType
+	case *ast.InterfaceType:
+		// The empty interface is the same as any, which is mostly useful
+		// for type parameter constraints.
+		if e.Methods == nil || len(e.Methods.List) == 0 {
+			return FunctionArg{Name: "any"}
+		}
 	}
 	return FunctionArg{}
 }
 
-// starOrSelect is a helper that partially fills in the FunctionArg
-// for either a Star (pointer) or simple non-pointer param type.
-func starOrSelect(c map[string]string, e ast.Expr) FunctionArg {
-	switch e := e.(type) {
-	case *ast.StarExpr:
-		if s, ok := e.X.(*ast.SelectorExpr); ok {
-			if im, ok := s.X.(*ast.Ident); ok {
-				return FunctionArg{ImportPath: c[im.Name], Name: s.Sel.String(), Pointer: true}
-			}
+// funcTypeToSignature maps the parameters and results of a function type to a
+// FunctionSignature.
+func funcTypeToSignature(c map[string]string, f *ast.FuncType) FunctionSignature {
+	fs := FunctionSignature{}
+	if f.Params != nil {
+		for _, p := range f.Params.List {
+			fs.In = append(fs.In, expandNames(p, typeToFunctionArg(c, p.Type))...)
 		}
-	case *ast.SelectorExpr:
-		if im, ok := e.X.(*ast.Ident); ok {
-			return FunctionArg{ImportPath: c[im.Name], Name: e.Sel.String()}
+	}
+	if f.Results != nil {
+		for _, r := range f.Results.List {
+			fs.Out = append(fs.Out, expandNames(r, typeToFunctionArg(c, r.Type))...)
 		}
 	}
-	log.Fatalf("Invalid expression given to startOrSelect: %T\n", e)
-	return FunctionArg{}
+	return fs
+}
+
+// expandNames returns an argument of type t for each of the names of the
+// field, since a single field can declare multiple parameters of the same
+// type, like "a, b int". Unnamed fields result in a single unnamed argument.
+func expandNames(field *ast.Field, t FunctionArg) []FunctionArg {
+	if len(field.Names) == 0 {
+		return []FunctionArg{t}
+	}
+	args := make([]FunctionArg, 0, len(field.Names))
+	for _, name := range field.Names {
+		t.ParamName = name.Name
+		args = append(args, t)
+	}
+	return args
 }
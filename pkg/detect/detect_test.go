@@ -1,6 +1,15 @@
 package detect
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+)
 
 const signatureFileJSON = "./testdata/signatures.json"
 const signatureFileYAML = "./testdata/signatures.yaml"
@@ -36,41 +45,264 @@ var tests = map[string]*FunctionDetails{
 		Name:      "Receive",
 		Signature: "func(http.ResponseWriter, *http.Request)",
 	},
+	"./testdata/f11-slice.go": &FunctionDetails{
+		Name:      "Process",
+		Signature: "func(context.Context, []byte) ([]byte, error)",
+	},
+	"./testdata/f12-composite.go": &FunctionDetails{
+		Name:      "Configure",
+		Signature: "func(map[string]string, [4]int, func(error))",
+	},
+	"./testdata/f13-bad.go": nil,
+	"./testdata/f14-variadic.go": &FunctionDetails{
+		Name:      "Run",
+		Signature: "func(context.Context, ...string) error",
+	},
+	"./testdata/f15-slice.go": nil,
+	"./testdata/f17-generic.go": &FunctionDetails{
+		Name:       "Handle",
+		Signature:  "func[T any](context.Context, T) (T, error)",
+		TypeParams: []TypeParam{{Name: "T", Constraint: FunctionArg{Name: "any"}}},
+	},
+	"./testdata/f18-generic.go": &FunctionDetails{
+		Name:       "HandleRenamed",
+		Signature:  "func[T any](context.Context, T) (T, error)",
+		TypeParams: []TypeParam{{Name: "X", Constraint: FunctionArg{Name: "any"}}},
+	},
+}
+
+// Valid function signatures
+// func(http.ResponseWriter, *http.Request)
+// func(v2.Event) (*v2.Event, error)
+var validFunctions = []FunctionSignature{
+	{In: []FunctionArg{
+		{ImportPath: "net/http", Name: "ResponseWriter"},
+		{ImportPath: "net/http", Name: "Request", Pointer: true},
+	}, Out: []FunctionArg{}},
+	{In: []FunctionArg{
+		{ImportPath: "context", Name: "Context"},
+		{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event"},
+	}, Out: []FunctionArg{
+		{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
+		{Name: "error"},
+	}},
+	{In: []FunctionArg{
+		{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event"},
+	}, Out: []FunctionArg{
+		{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
+		{Name: "error"},
+	}},
+	{In: []FunctionArg{
+		{ImportPath: "context", Name: "Context"},
+		{ImportPath: "github.com/mattmoor/korpc-sample/gen/proto", Name: "Request", Pointer: true, Channel: Receive},
+		{ImportPath: "github.com/mattmoor/korpc-sample/gen/proto", Name: "Response", Pointer: true, Channel: Both},
+	}, Out: []FunctionArg{
+		{Name: "error"},
+	}},
+	{In: []FunctionArg{
+		{ImportPath: "context", Name: "Context"},
+		{Slice: true, Elem: &FunctionArg{Name: "byte"}},
+	}, Out: []FunctionArg{
+		{Slice: true, Elem: &FunctionArg{Name: "byte"}},
+		{Name: "error"},
+	}},
+	{In: []FunctionArg{
+		{Key: &FunctionArg{Name: "string"}, Elem: &FunctionArg{Name: "string"}},
+		{ArrayLen: 4, Elem: &FunctionArg{Name: "int"}},
+		{Func: &FunctionSignature{In: []FunctionArg{{Name: "error"}}}},
+	}},
+	{In: []FunctionArg{
+		{ImportPath: "context", Name: "Context"},
+		{Name: "string", Variadic: true},
+	}, Out: []FunctionArg{
+		{Name: "error"},
+	}},
+	{TypeParams: []TypeParam{
+		{Name: "T", Constraint: FunctionArg{Name: "any"}},
+	}, In: []FunctionArg{
+		{ImportPath: "context", Name: "Context"},
+		{Name: "T"},
+	}, Out: []FunctionArg{
+		{Name: "T"},
+		{Name: "error"},
+	}},
 }
 
 func TestAllCases(t *testing.T) {
-	// Valid function signatures
-	// func(http.ResponseWriter, *http.Request)
-	// func(v2.Event) (*v2.Event, error)
-	var validFunctions = []FunctionSignature{
+	d := NewDetector(validFunctions)
+	runTests(t, d)
+}
+
+func TestVariadicAsSlice(t *testing.T) {
+	d := NewDetector(validFunctions, WithVariadicAsSlice())
+	runTestCases(t, d, map[string]*FunctionDetails{
+		"./testdata/f14-variadic.go": &FunctionDetails{
+			Name:      "Run",
+			Signature: "func(context.Context, ...string) error",
+		},
+		"./testdata/f15-slice.go": &FunctionDetails{
+			Name:      "RunSlice",
+			Signature: "func(context.Context, ...string) error",
+		},
+	})
+}
+
+const wildcardSignatures = `
+functionSignatures:
+  - in:
+      - importPath: context
+        name: Context
+      - name: "*"
+    out:
+      - name: error
+  - in:
+      - importPath: context
+        name: Context
+      - importPath: example.com/api
+        name: "*"
+        pointer: true
+    out:
+      - name: "*"
+        pointer: true
+      - name: error
+  - in:
+      - importPath: context
+        name: Context
+      - importPath: example.com/other
+        name: "*"
+        pointer: true
+    out:
+      - name: "*"
+      - name: "*"
+`
+
+func TestWildcards(t *testing.T) {
+	d, err := NewDetectorFromString(wildcardSignatures)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures: %s", err)
+	}
+	want := []FunctionDetails{
+		{
+			Name:      "Handle",
+			Signature: "func(context.Context, _) error",
+			Wildcards: []FunctionArg{{Name: "Request", ParamName: "req"}},
+		},
+		{
+			Name:      "HandleAPI",
+			Signature: "func(context.Context, *api._) (*_, error)",
+			Wildcards: []FunctionArg{
+				{ImportPath: "example.com/api", Name: "Request", Pointer: true, ParamName: "req"},
+				{ImportPath: "example.com/api", Name: "Response", Pointer: true},
+			},
+		},
+	}
+	got, err := d.ReadAllFromFile("./testdata/f16-wildcard.go")
+	if err != nil {
+		t.Fatalf("Expected no error detecting signatures, got %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Wanted %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Signature != want[i].Signature || !reflect.DeepEqual(got[i].Wildcards, want[i].Wildcards) {
+			t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestGroupedParams(t *testing.T) {
+	d := NewDetector([]FunctionSignature{
 		{In: []FunctionArg{
 			{ImportPath: "net/http", Name: "ResponseWriter"},
 			{ImportPath: "net/http", Name: "Request", Pointer: true},
-		}, Out: []FunctionArg{}},
-		{In: []FunctionArg{
-			{ImportPath: "context", Name: "Context"},
-			{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event"},
-		}, Out: []FunctionArg{
-			{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
-			{Name: "error"},
 		}},
 		{In: []FunctionArg{
-			{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event"},
-		}, Out: []FunctionArg{
-			{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
-			{Name: "error"},
+			{ImportPath: "net/http", Name: "ResponseWriter"},
+			{Name: "int"},
+			{Name: "int"},
 		}},
+	})
+	got, err := d.ReadAllFromFile("./testdata/f19-grouped.go")
+	if err != nil {
+		t.Fatalf("Expected no error detecting signatures, got %v", err)
+	}
+	// Two has three parameters, so it must not match.
+	want := []FunctionDetails{
+		{
+			Name:      "Sum",
+			Signature: "func(http.ResponseWriter, int, int)",
+			Params: []FunctionArg{
+				{ImportPath: "net/http", Name: "ResponseWriter", ParamName: "w"},
+				{Name: "int", ParamName: "a"},
+				{Name: "int", ParamName: "b"},
+			},
+		},
+		{
+			Name:      "Named",
+			Signature: "func(http.ResponseWriter, *http.Request)",
+			Params: []FunctionArg{
+				{ImportPath: "net/http", Name: "ResponseWriter", ParamName: "resp"},
+				{ImportPath: "net/http", Name: "Request", Pointer: true, ParamName: "req"},
+			},
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Wanted %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Signature != want[i].Signature || !reflect.DeepEqual(got[i].Params, want[i].Params) {
+			t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParamNames(t *testing.T) {
+	d := NewDetector([]FunctionSignature{
 		{In: []FunctionArg{
-			{ImportPath: "context", Name: "Context"},
-			{ImportPath: "github.com/mattmoor/korpc-sample/gen/proto", Name: "Request", Pointer: true, Channel: Receive},
-			{ImportPath: "github.com/mattmoor/korpc-sample/gen/proto", Name: "Response", Pointer: true, Channel: Both},
-		}, Out: []FunctionArg{
-			{Name: "error"},
+			{ImportPath: "net/http", Name: "ResponseWriter", ParamName: "resp"},
+			{ImportPath: "net/http", Name: "Request", Pointer: true, ParamName: "req"},
 		}},
-	}
+	})
+	runTestCases(t, d, map[string]*FunctionDetails{
+		"./testdata/f1.go": nil,
+		"./testdata/f19-grouped.go": &FunctionDetails{
+			Name:      "Named",
+			Signature: "func(resp http.ResponseWriter, req *http.Request)",
+		},
+	})
+}
 
-	d := NewDetector(validFunctions)
-	runTests(t, d)
+func TestRoundTrip(t *testing.T) {
+	want := FunctionSignatures{FunctionSignatures: validFunctions}
+	encoded := map[string]func() ([]byte, error){
+		"json": func() ([]byte, error) { return json.Marshal(want) },
+		"yaml": func() ([]byte, error) { return yaml.Marshal(want) },
+		"toml": func() ([]byte, error) {
+			var buf bytes.Buffer
+			err := toml.NewEncoder(&buf).Encode(want)
+			return buf.Bytes(), err
+		},
+	}
+	for format, encode := range encoded {
+		t.Run(format, func(t *testing.T) {
+			config, err := encode()
+			if err != nil {
+				t.Fatalf("Failed to encode signatures: %s", err)
+			}
+			d, err := NewDetectorFromString(string(config))
+			if err != nil {
+				t.Fatalf("Failed to decode signatures: %s\n%s", err, config)
+			}
+			if len(d.sigs) != len(validFunctions) {
+				t.Fatalf("Wanted %d signatures, got %d", len(validFunctions), len(d.sigs))
+			}
+			for i := range validFunctions {
+				if !d.sigs[i].matches(&validFunctions[i], nil) {
+					t.Errorf("Error at %d, wanted %s, got %s", i, validFunctions[i].String(), d.sigs[i].String())
+				}
+			}
+		})
+	}
 }
 
 func TestAllCasesFromJSONFile(t *testing.T) {
@@ -121,8 +353,52 @@ func TestAllCasesFromTOML(t *testing.T) {
 	runTests(t, d)
 }
 
+// typeCheckedTests are the additional cases that can only be detected when the
+// types are resolved.
+var typeCheckedTests = map[string]*FunctionDetails{
+	"./testdata/f9-alias.go": &FunctionDetails{
+		Name:      "ReceiveAlias",
+		Signature: "func(http.ResponseWriter, *http.Request)",
+	},
+	"./testdata/f10-dot.go": &FunctionDetails{
+		Name:      "ReceiveDot",
+		Signature: "func(http.ResponseWriter, *http.Request)",
+	},
+}
+
+func TestAllCasesTypeChecked(t *testing.T) {
+	d, err := NewDetectorFromFile(signatureFileYAML, WithTypeChecking())
+	if err != nil {
+		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileYAML, err)
+	}
+	// Types that can't be resolved (cloudevents, korpc) fall back to the
+	// syntax, so all the usual cases must still work.
+	runTests(t, d)
+	runTestCases(t, d, typeCheckedTests)
+}
+
+func TestTypeCheckedCasesNotDetectedFromSyntax(t *testing.T) {
+	d, err := NewDetectorFromFile(signatureFileYAML)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileYAML, err)
+	}
+	for file := range typeCheckedTests {
+		got, err := d.ReadAndCheckFile(file)
+		if err != nil {
+			t.Fatalf("Failed to check file: %s", err)
+		}
+		if got != nil {
+			t.Errorf("%s: expected nil without type checking but got %+v", file, got)
+		}
+	}
+}
+
 func runTests(t *testing.T, d *Detector) {
-	for file, expected := range tests {
+	runTestCases(t, d, tests)
+}
+
+func runTestCases(t *testing.T, d *Detector, cases map[string]*FunctionDetails) {
+	for file, expected := range cases {
 		t.Run(file, func(t *testing.T) {
 			got, err := d.ReadAndCheckFile(file)
 			if err != nil {
@@ -139,6 +415,9 @@ func runTests(t *testing.T, d *Detector) {
 				if expected.Signature != got.Signature {
 					t.Errorf("Signature differs got %q expected %q", got.Signature, expected.Signature)
 				}
+				if !reflect.DeepEqual(expected.TypeParams, got.TypeParams) {
+					t.Errorf("TypeParams differ got %+v expected %+v", got.TypeParams, expected.TypeParams)
+				}
 			}
 		})
 	}
@@ -150,17 +429,34 @@ func TestMultiDetect(t *testing.T) {
 		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileJSON, err)
 	}
 
+	testfile := "./testdata/multi-fn.go"
 	want := []FunctionDetails{
 		{
+			Kind:      KindFunction,
 			Name:      "ReceiveHTTP",
 			Signature: "func(http.ResponseWriter, *http.Request)",
+			Match:     &d.sigs[0],
+			Position:  token.Position{Filename: testfile, Offset: 102, Line: 9, Column: 6},
+			Params: []FunctionArg{
+				{ImportPath: "net/http", Name: "ResponseWriter", ParamName: "writer"},
+				{ImportPath: "net/http", Name: "Request", Pointer: true, ParamName: "request"},
+			},
 		},
 		{
+			Kind:      KindFunction,
 			Name:      "ReceiveEvent",
 			Signature: "func(v2.Event) (*v2.Event, error)",
+			Match:     &d.sigs[1],
+			Position:  token.Position{Filename: testfile, Offset: 208, Line: 13, Column: 6},
+			Params: []FunctionArg{
+				{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", ParamName: "ce"},
+			},
+			Results: []FunctionArg{
+				{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
+				{Name: "error"},
+			},
 		},
 	}
-	testfile := "./testdata/multi-fn.go"
 
 	if got, err := d.ReadAndCheckFile(testfile); err == nil {
 		t.Errorf("Expected checking error on multi-file, got: %+v", got)
@@ -174,7 +470,7 @@ func TestMultiDetect(t *testing.T) {
 		t.Errorf("Wanted %v, got %v", want, got)
 	}
 	for i := range want {
-		if want[i] != got[i] {
+		if !reflect.DeepEqual(want[i], got[i]) {
 			t.Errorf("Error at %d, wanted %v, got %v", i, want[i], got[i])
 		}
 	}
@@ -0,0 +1,136 @@
+package detect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Diagnostic explains why a function that almost matches one of the
+// signatures doesn't.
+type Diagnostic struct {
+	// Name is the name of the function.
+	Name string
+	// Position is where the function is declared.
+	Position token.Position
+	// Signature is the signature that the function came closest to.
+	Signature string
+	// Closest is the signature that the function came closest to, for its
+	// ID and metadata.
+	Closest *FunctionSignature
+	// Reasons explain each of the differences, for example
+	// "arg 2 is `http.Request`, expected `*http.Request`".
+	Reasons []string
+	// Mismatches are the arguments and results that differ from the
+	// signature.
+	Mismatches []Mismatch
+}
+
+// Mismatch is an argument or result of a function that differs from the one
+// of the signature at the same position.
+type Mismatch struct {
+	// Result is set if it's a result rather than an argument.
+	Result bool
+	// Index is the position of the argument or result, starting at 0.
+	Index int
+	Got   FunctionArg
+	Want  FunctionArg
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s does not match %s: %s", d.Position, d.Name, d.Signature, strings.Join(d.Reasons, ", "))
+}
+
+// diagnose returns a Diagnostic for the function f, which did not match any
+// of the signatures, if it's a near miss. That is an exported function that
+// has the same number of arguments and results as one of the signatures, or
+// where at least half of them match. Unexported functions are only reported
+// if they would match otherwise, see WithExportedOnly. If there are multiple
+// candidates, the signature with the fewest differences wins.
+func (d *Detector) diagnose(fset *token.FileSet, resolver *argResolver, f *ast.FuncDecl) *Diagnostic {
+	fs := resolver.signature(f.Type)
+	if d.variadicAsSlice {
+		fs = fs.variadicAsSlice()
+	}
+	sigs := d.sigs
+	if resolver.sigs != nil {
+		sigs = resolver.sigs
+	}
+
+	var best *Diagnostic
+	for i, v := range sigs {
+		if d.variadicAsSlice {
+			v = v.variadicAsSlice()
+		}
+		reasons, mismatches, near := differences(&v, &fs, f.Recv != nil)
+		if !f.Name.IsExported() {
+			if len(reasons) > 0 {
+				continue
+			}
+			reasons = []string{fmt.Sprintf("function `%s` is unexported", f.Name.Name)}
+		} else if !near || len(reasons) == 0 {
+			continue
+		}
+		if best == nil || len(reasons) < len(best.Reasons) {
+			closest := d.sigs[i]
+			best = &Diagnostic{
+				Name:       f.Name.Name,
+				Position:   fset.Position(f.Name.Pos()),
+				Signature:  d.sigs[i].String(),
+				Closest:    &closest,
+				Reasons:    reasons,
+				Mismatches: mismatches,
+			}
+		}
+	}
+	return best
+}
+
+// differences returns the reasons why actual does not match the signature
+// fs, the arguments and results that differ and whether it's close enough to
+// be worth reporting. recv tells whether actual is a method.
+func differences(fs, actual *FunctionSignature, recv bool) ([]string, []Mismatch, bool) {
+	var reasons []string
+	if fs.Receiver == ReceiverRequired && !recv {
+		reasons = append(reasons, "it is a function, expected a method")
+	}
+	if fs.Receiver == ReceiverForbidden && recv {
+		reasons = append(reasons, "it is a method, expected a function")
+	}
+	if len(fs.TypeParams) != len(actual.TypeParams) {
+		reasons = append(reasons, fmt.Sprintf("has %d type parameters, expected %d", len(actual.TypeParams), len(fs.TypeParams)))
+	}
+	inReasons, inMismatches, inMatched := argDifferences(false, fs.In, actual.In)
+	outReasons, outMismatches, outMatched := argDifferences(true, fs.Out, actual.Out)
+	reasons = append(append(reasons, inReasons...), outReasons...)
+
+	sameArity := len(fs.In) == len(actual.In) && len(fs.Out) == len(actual.Out)
+	total := max(len(fs.In), len(actual.In)) + max(len(fs.Out), len(actual.Out))
+	mostMatch := total > 0 && (inMatched+outMatched)*2 >= total
+	return reasons, append(inMismatches, outMismatches...), sameArity || mostMatch
+}
+
+// argDifferences compares the arguments (or results, if result is set) one
+// by one and returns the differences along with how many of them matched.
+func argDifferences(result bool, want, actual []FunctionArg) ([]string, []Mismatch, int) {
+	what := "arg"
+	if result {
+		what = "result"
+	}
+	var reasons []string
+	var mismatches []Mismatch
+	matched := 0
+	if len(want) != len(actual) {
+		reasons = append(reasons, fmt.Sprintf("has %d %ss, expected %d", len(actual), what, len(want)))
+	}
+	for i := 0; i < len(want) && i < len(actual); i++ {
+		if want[i].matches(&actual[i], nil) {
+			matched++
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s %d is `%s`, expected `%s`", what, i+1, actual[i].String(), want[i].String()))
+		mismatches = append(mismatches, Mismatch{Result: result, Index: i, Got: actual[i], Want: want[i]})
+	}
+	return reasons, mismatches, matched
+}
@@ -0,0 +1,45 @@
+package detect
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	sig := httpSignature("")
+	sig.ID = "http"
+	d := NewDetector([]FunctionSignature{sig}, WithExportedOnly())
+	p, err := d.ScanPackage("./testdata/diagnostics")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	if len(p.Matches) != 0 {
+		t.Errorf("Wanted no matches, got %+v", p.Matches)
+	}
+	// Unrelated and helper are not even close.
+	want := []Diagnostic{
+		{Name: "receive", Reasons: []string{"function `receive` is unexported"}},
+		{Name: "Receive", Reasons: []string{"arg 2 is `http.Request`, expected `*http.Request`"}},
+		{Name: "ReceiveMore", Reasons: []string{"has 3 args, expected 2"}},
+	}
+	wantLines := []int{7, 10, 13}
+	if len(p.Diagnostics) != len(want) {
+		t.Fatalf("Wanted %+v, got %+v", want, p.Diagnostics)
+	}
+	for i := range want {
+		got := p.Diagnostics[i]
+		if got.Name != want[i].Name || !reflect.DeepEqual(got.Reasons, want[i].Reasons) {
+			t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got)
+		}
+		if got.Signature != "func(http.ResponseWriter, *http.Request)" {
+			t.Errorf("Error at %d, unexpected signature %q", i, got.Signature)
+		}
+		if got.Closest == nil || got.Closest.ID != "http" {
+			t.Errorf("Error at %d, wanted the closest signature to be http, got %+v", i, got.Closest)
+		}
+		if filepath.Base(got.Position.Filename) != "near.go" || got.Position.Line != wantLines[i] {
+			t.Errorf("Error at %d, wanted line %d, got %s", i, wantLines[i], got.Position)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package detect
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// ErrUnsupportedType is returned (wrapped) for types that can't be
+// expressed as a FunctionArg, like channels of channels or interfaces with
+// methods.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// UnsupportedTypeError is reported for the arguments and results of a
+// function that use an unsupported type. Such a function can not match any
+// of the signatures.
+type UnsupportedTypeError struct {
+	// Name is the name of the function.
+	Name string
+	// Position is where the type is.
+	Position token.Position
+	// Type is the type as written in the source.
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("%s: %s uses unsupported type `%s`", e.Position, e.Name, e.Type)
+}
+
+func (e *UnsupportedTypeError) Unwrap() error {
+	return ErrUnsupportedType
+}
+
+// unsupportedTypes returns an error for each of the arguments and results of
+// the function type ft of the function (or variable) name that have an
+// unsupported type.
+func unsupportedTypes(fset *token.FileSet, resolver *argResolver, name string, ft *ast.FuncType) []error {
+	var errs []error
+	for _, fields := range []*ast.FieldList{ft.Params, ft.Results} {
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			if fa := resolver.resolve(field.Type); !fa.supported() {
+				errs = append(errs, &UnsupportedTypeError{
+					Name:     name,
+					Position: fset.Position(field.Type.Pos()),
+					Type:     types.ExprString(field.Type),
+				})
+			}
+		}
+	}
+	return errs
+}
+
+// unsupportedSignature returns the errors for the unsupported types of fs,
+// the signature of the function that the variable or factory name holds or
+// returns, which is declared as e. If e spells out the function type, each
+// of its arguments and results is checked, otherwise e is reported as a
+// whole, for example a func type declared with an unsupported type.
+func unsupportedSignature(fset *token.FileSet, resolver *argResolver, name string, e ast.Expr, fs FunctionSignature) []error {
+	switch e := e.(type) {
+	case *ast.FuncType:
+		return unsupportedTypes(fset, resolver, name, e)
+	case *ast.FuncLit:
+		return unsupportedTypes(fset, resolver, name, e.Type)
+	}
+	for _, args := range [][]FunctionArg{fs.In, fs.Out} {
+		for i := range args {
+			if !args[i].supported() {
+				return []error{&UnsupportedTypeError{Name: name, Position: fset.Position(e.Pos()), Type: types.ExprString(e)}}
+			}
+		}
+	}
+	return nil
+}
+
+// supported reports whether fa, and all the types it's made of, could be
+// mapped. typeToFunctionArg returns an empty FunctionArg for what it can't
+// map.
+func (fa *FunctionArg) supported() bool {
+	if fa.Func != nil {
+		for _, args := range [][]FunctionArg{fa.Func.In, fa.Func.Out} {
+			for i := range args {
+				if !args[i].supported() {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if fa.Name == "" && !fa.Slice && fa.ArrayLen == 0 && fa.Key == nil {
+		return false
+	}
+	for _, elem := range []*FunctionArg{fa.Key, fa.Elem} {
+		if elem != nil && !elem.supported() {
+			return false
+		}
+	}
+	return true
+}
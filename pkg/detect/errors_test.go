@@ -0,0 +1,90 @@
+package detect
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestUnsupportedTypes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	d := NewDetector([]FunctionSignature{httpSignature("")}, WithLogger(logger), WithExportedOnly())
+	p, err := d.ScanPackage("./testdata/unsupported")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	if len(p.Matches) != 1 || p.Matches[0].Name != "Receive" {
+		t.Errorf("Wanted Receive to match, got %+v", p.Matches)
+	}
+	// The unexported pipe is left out.
+	if len(p.Errors) != 1 {
+		t.Fatalf("Wanted 1 error, got %v", p.Errors)
+	}
+	var unsupported *UnsupportedTypeError
+	if !errors.As(p.Errors[0], &unsupported) || !errors.Is(p.Errors[0], ErrUnsupportedType) {
+		t.Fatalf("Wanted an UnsupportedTypeError, got %v", p.Errors[0])
+	}
+	if unsupported.Name != "Pipe" || unsupported.Type != "chan chan int" || unsupported.Position.Line != 10 {
+		t.Errorf("Unexpected error %+v", unsupported)
+	}
+
+	for _, want := range []string{"Checking function signature", "Found matching signature", "Skipping unsupported type"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Wanted %q to be logged, got %s", want, buf.String())
+		}
+	}
+}
+
+func TestUnsupportedTypesDontMatch(t *testing.T) {
+	// The wildcard would match the chan chan int of Pipe, which is
+	// reported instead.
+	d := NewDetector([]FunctionSignature{{In: []FunctionArg{
+		{Name: Wildcard},
+		{Name: "int", Channel: Send},
+	}}})
+	p, err := d.ScanPackage("./testdata/unsupported")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	if len(p.Matches) != 0 {
+		t.Errorf("Wanted no matches, got %+v", p.Matches)
+	}
+	if len(p.Errors) != 2 || !errors.Is(p.Errors[0], ErrUnsupportedType) || !errors.Is(p.Errors[1], ErrUnsupportedType) {
+		t.Errorf("Wanted two unsupported types, got %v", p.Errors)
+	}
+}
+
+func TestScanFile(t *testing.T) {
+	d := NewDetector([]FunctionSignature{httpSignature("")}, WithExportedOnly())
+	p, err := d.ScanFile("./testdata/unsupported/chans.go")
+	if err != nil {
+		t.Fatalf("Failed to scan file: %s", err)
+	}
+	const importPath = "github.com/vaikas/gofunctypechecker/pkg/detect/testdata/unsupported"
+	if p.ImportPath != importPath || p.Name != "unsupported" {
+		t.Errorf("Wanted package unsupported at %s, got %s at %s", importPath, p.Name, p.ImportPath)
+	}
+	if len(p.Matches) != 1 || p.Matches[0].Name != "Receive" || p.Matches[0].Package != importPath {
+		t.Errorf("Wanted Receive to match, got %+v", p.Matches)
+	}
+	// Unlike AllFromFile, the unsupported types and near misses are kept.
+	if len(p.Errors) != 1 || !errors.Is(p.Errors[0], ErrUnsupportedType) {
+		t.Errorf("Wanted an unsupported type, got %v", p.Errors)
+	}
+	if p, err = d.ScanFile("./testdata/diagnostics/near.go"); err != nil {
+		t.Fatalf("Failed to scan file: %s", err)
+	}
+	if len(p.Diagnostics) == 0 {
+		t.Error("Wanted the near misses")
+	}
+}
+
+func TestParseUnsupportedType(t *testing.T) {
+	_, err := ParseSignature("func(chan chan int)", nil)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Wanted ErrUnsupportedType, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package detect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+)
+
+// Format is one of the formats the signatures can be written in.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// Encode writes the signatures to w in the given format.
+func (fs *FunctionSignatures) Encode(w io.Writer, format Format) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(fs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case YAML:
+		data, err := yaml.Marshal(fs)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case TOML:
+		return toml.NewEncoder(w).Encode(fs)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
@@ -0,0 +1,152 @@
+package detect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+)
+
+// GenerateSignatures returns the signatures for the declarations with the
+// given names in the package in dir, so that they don't have to be written
+// by hand. A name can refer to a func type, like http.HandlerFunc, to a
+// function, or to an interface, which results in an InterfaceSignature. The
+// types declared in the package itself are referred to by the import path of
+// the package, so dir must be part of a module for those. Parameter names are
+// left out, so that the signatures match regardless of them.
+func GenerateSignatures(dir string, names ...string) (*FunctionSignatures, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	importPath, err := packageImportPath(bp, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the declarations along with the imports of the file they're in.
+	type decl struct {
+		imports map[string]string
+		node    ast.Node
+		// typeParams are the type parameters of a generic type.
+		typeParams *ast.FieldList
+	}
+	decls := make(map[string]decl)
+	local := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		astFile, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		imports := fileImports(astFile)
+		for _, d := range astFile.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					decls[d.Name.Name] = decl{imports: imports, node: d.Type}
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						local[ts.Name.Name] = true
+						decls[ts.Name.Name] = decl{imports: imports, node: ts.Type, typeParams: ts.TypeParams}
+					}
+				}
+			}
+		}
+	}
+
+	ret := &FunctionSignatures{}
+	for _, name := range names {
+		d, ok := decls[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not declared in %s", name, dir)
+		}
+		resolver := &argResolver{imports: d.imports}
+		switch node := d.node.(type) {
+		case *ast.FuncType:
+			ft := *node
+			if d.typeParams != nil {
+				ft.TypeParams = d.typeParams
+			}
+			sig := resolver.signature(&ft)
+			if err := sig.generated(importPath, local); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			ret.FunctionSignatures = append(ret.FunctionSignatures, sig)
+		case *ast.InterfaceType:
+			iface := InterfaceSignature{}
+			for _, m := range node.Methods.List {
+				ft, ok := m.Type.(*ast.FuncType)
+				if !ok || len(m.Names) != 1 {
+					return nil, fmt.Errorf("%s: embedded interfaces are not supported", name)
+				}
+				sig := resolver.signature(ft)
+				if err := sig.generated(importPath, local); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", name, m.Names[0].Name, err)
+				}
+				iface.Methods = append(iface.Methods, MethodSignature{Name: m.Names[0].Name, FunctionSignature: sig})
+			}
+			ret.Interfaces = append(ret.Interfaces, iface)
+		default:
+			return nil, fmt.Errorf("%s is not a func type, function or interface", name)
+		}
+	}
+	return ret, nil
+}
+
+// generated fixes up a signature built from a declaration in the package
+// importPath: the types declared in the package (local) get its import path
+// and the parameter names are dropped. It returns an error if any of the
+// types can't be expressed as a FunctionArg.
+func (fs *FunctionSignature) generated(importPath string, local map[string]bool) error {
+	// Type parameters shadow the types of the package.
+	typeParams := make(map[string]bool)
+	for _, tp := range fs.TypeParams {
+		typeParams[tp.Name] = true
+	}
+	var fix func(fa *FunctionArg) error
+	fix = func(fa *FunctionArg) error {
+		fa.ParamName = ""
+		if fa.ImportPath == "" && local[fa.Name] && !typeParams[fa.Name] {
+			if importPath == "" {
+				return fmt.Errorf("%s is declared in a package that's not part of a module", fa.Name)
+			}
+			fa.ImportPath = importPath
+		}
+		for _, elem := range []*FunctionArg{fa.Key, fa.Elem} {
+			if elem != nil {
+				if err := fix(elem); err != nil {
+					return err
+				}
+			}
+		}
+		if fa.Func != nil {
+			for _, args := range [][]FunctionArg{fa.Func.In, fa.Func.Out} {
+				for i := range args {
+					if err := fix(&args[i]); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for i := range fs.TypeParams {
+		if err := fix(&fs.TypeParams[i].Constraint); err != nil {
+			return err
+		}
+	}
+	for _, args := range [][]FunctionArg{fs.In, fs.Out} {
+		for i := range args {
+			if err := fix(&args[i]); err != nil {
+				return err
+			}
+		}
+	}
+	// The same checks as for a parsed signature.
+	return fs.fromSyntax()
+}
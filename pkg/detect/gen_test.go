@@ -0,0 +1,72 @@
+package detect
+
+import (
+	"bytes"
+	"go/build"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const genDir = "./testdata/gen"
+
+func TestGenerateSignatures(t *testing.T) {
+	got, err := GenerateSignatures(genDir, "HandlerFunc", "EventHandler", "Transform", "Receive", "Handler")
+	if err != nil {
+		t.Fatalf("Failed to generate signatures: %s", err)
+	}
+	want := []string{
+		"func(http.ResponseWriter, *http.Request)",
+		"func(context.Context, gen.Event) (*v2.Event, error)",
+		"func[T any](context.Context, T) ([]T, error)",
+		"func(context.Context, v2.Event) error",
+	}
+	if len(got.FunctionSignatures) != len(want) {
+		t.Fatalf("Wanted %v, got %+v", want, got.FunctionSignatures)
+	}
+	for i := range want {
+		if s := got.FunctionSignatures[i].String(); s != want[i] {
+			t.Errorf("Error at %d, wanted %q, got %q", i, want[i], s)
+		}
+	}
+	if event := got.FunctionSignatures[1].In[1]; event.ImportPath != "github.com/vaikas/gofunctypechecker/pkg/detect/testdata/gen" {
+		t.Errorf("Wanted the local type to get the import path of the package, got %+v", event)
+	}
+	if len(got.Interfaces) != 1 || got.Interfaces[0].String() != "interface{ ServeHTTP(http.ResponseWriter, *http.Request) }" {
+		t.Errorf("Wanted the Handler interface, got %+v", got.Interfaces)
+	}
+
+	// The generated signatures read back the same in all the formats.
+	for _, format := range []Format{JSON, YAML, TOML} {
+		var buf bytes.Buffer
+		if err := got.Encode(&buf, format); err != nil {
+			t.Fatalf("Failed to encode %s: %s", format, err)
+		}
+		d, err := NewDetectorFromString(buf.String())
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s\n%s", format, err, buf.String())
+		}
+		if !strings.HasPrefix(d.Signatures(), strings.Join(want, "\n")) {
+			t.Errorf("%s: wanted %v, got %s", format, want, d.Signatures())
+		}
+	}
+}
+
+func TestGenerateSignaturesGoroot(t *testing.T) {
+	got, err := GenerateSignatures(filepath.Join(build.Default.GOROOT, "src", "net", "http"), "HandlerFunc")
+	if err != nil {
+		t.Fatalf("Failed to generate signatures: %s", err)
+	}
+	// Not std/net/http, which the go.mod of GOROOT would give.
+	if w := got.FunctionSignatures[0].In[0]; w.ImportPath != "net/http" || w.Name != "ResponseWriter" {
+		t.Errorf("Wanted net/http.ResponseWriter, got %+v", w)
+	}
+}
+
+func TestGenerateSignaturesErrors(t *testing.T) {
+	for _, name := range []string{"NotAFunc", "Missing"} {
+		if _, err := GenerateSignatures(genDir, name); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
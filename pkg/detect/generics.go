@@ -0,0 +1,51 @@
+package detect
+
+// TypeParam describes a type parameter of a generic function, for example
+// the "T any" of "func[T any](context.Context, T) (T, error)" would be:
+// TypeParam{Name: "T", Constraint: FunctionArg{Name: "any"}}
+type TypeParam struct {
+	Name       string      `json:"name" toml:"name"`
+	Constraint FunctionArg `json:"constraint" toml:"constraint"`
+}
+
+func (tp *TypeParam) String() string {
+	return tp.Name + " " + tp.Constraint.String()
+}
+
+// renameTypeParams returns a copy of fs where the references to the type
+// parameters are renamed according to names.
+func renameTypeParams(fs FunctionSignature, names map[string]string) FunctionSignature {
+	ret := FunctionSignature{}
+	for _, tp := range fs.TypeParams {
+		tp.Name = names[tp.Name]
+		tp.Constraint = renameTypeParamsInArg(tp.Constraint, names)
+		ret.TypeParams = append(ret.TypeParams, tp)
+	}
+	for _, in := range fs.In {
+		ret.In = append(ret.In, renameTypeParamsInArg(in, names))
+	}
+	for _, out := range fs.Out {
+		ret.Out = append(ret.Out, renameTypeParamsInArg(out, names))
+	}
+	return ret
+}
+
+func renameTypeParamsInArg(fa FunctionArg, names map[string]string) FunctionArg {
+	// Type parameters are always referred to without an import path.
+	if name, ok := names[fa.Name]; ok && fa.ImportPath == "" {
+		fa.Name = name
+	}
+	if fa.Key != nil {
+		key := renameTypeParamsInArg(*fa.Key, names)
+		fa.Key = &key
+	}
+	if fa.Elem != nil {
+		elem := renameTypeParamsInArg(*fa.Elem, names)
+		fa.Elem = &elem
+	}
+	if fa.Func != nil {
+		sig := renameTypeParams(*fa.Func, names)
+		fa.Func = &sig
+	}
+	return fa
+}
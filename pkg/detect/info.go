@@ -0,0 +1,35 @@
+package detect
+
+import (
+	"sort"
+)
+
+// SignatureInfo describes a signature (or interface) for the tools using
+// the results, so that they can tell the matches apart without comparing the
+// rendered signatures.
+type SignatureInfo struct {
+	// ID identifies the signature, for example "cloudevents".
+	ID string `json:"id,omitempty" toml:"id,omitempty"`
+	// Description is a human readable description of the signature.
+	Description string `json:"description,omitempty" toml:"description,omitempty"`
+	// Priority decides which signature wins if a function matches more than
+	// one of them, the highest one does. Signatures with the same priority
+	// are tried in the order they're listed.
+	Priority int `json:"priority,omitempty" toml:"priority,omitzero"`
+	// Metadata is arbitrary data for the tools using the signatures, for
+	// example the protocol or the name of the build plan.
+	Metadata map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty"`
+}
+
+// byPriority returns the indexes of n signatures in the order they should be
+// tried, given the priority of each of them.
+func byPriority(n int, priority func(i int) int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return priority(order[a]) > priority(order[b])
+	})
+	return order
+}
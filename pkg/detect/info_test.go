@@ -0,0 +1,75 @@
+package detect
+
+import (
+	"testing"
+)
+
+const prioritizedSignatures = `
+imports:
+  http: net/http
+functionSignatures:
+  - id: any-request
+    description: HTTP handlers taking any kind of request
+    signature: func(http.ResponseWriter, *_)
+  - id: http
+    description: Plain HTTP handlers
+    signature: func(http.ResponseWriter, *http.Request)
+    priority: 10
+    metadata:
+      protocol: http
+      plan: http-go-function
+interfaces:
+  - id: handler
+    methods:
+      - name: ServeHTTP
+        signature: func(http.ResponseWriter, *_)
+  - id: http.Handler
+    priority: 1
+    methods:
+      - name: ServeHTTP
+        signature: func(http.ResponseWriter, *http.Request)
+`
+
+func TestPriority(t *testing.T) {
+	d, err := NewDetectorFromString(prioritizedSignatures)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures: %s", err)
+	}
+	got, err := d.ReadAndCheckFile("./testdata/f1.go")
+	if err != nil {
+		t.Fatalf("Failed to check file: %s", err)
+	}
+	if got == nil || got.Match == nil {
+		t.Fatalf("Wanted a match, got %+v", got)
+	}
+	if got.Match.ID != "http" || got.Match.Metadata["protocol"] != "http" || got.Match.Metadata["plan"] != "http-go-function" {
+		t.Errorf("Wanted the http signature with its metadata, got %+v", got.Match)
+	}
+	if got.Signature != "func(http.ResponseWriter, *http.Request)" {
+		t.Errorf("Wanted the signature of the match, got %q", got.Signature)
+	}
+
+	p, err := d.ScanPackage(interfacesDir)
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	for _, m := range p.Matches {
+		if m.Kind == KindType && (m.Interface == nil || m.Interface.ID != "http.Handler") {
+			t.Errorf("Wanted %s to implement http.Handler, got %+v", m.Name, m.Interface)
+		}
+	}
+}
+
+func TestSamePriority(t *testing.T) {
+	d := NewDetector([]FunctionSignature{
+		{SignatureInfo: SignatureInfo{ID: "first"}, In: []FunctionArg{{ImportPath: "net/http", Name: "ResponseWriter"}, {Name: Wildcard, Pointer: true}}},
+		{SignatureInfo: SignatureInfo{ID: "second"}, In: httpSignature("").In},
+	})
+	got, err := d.ReadAndCheckFile("./testdata/f1.go")
+	if err != nil {
+		t.Fatalf("Failed to check file: %s", err)
+	}
+	if got == nil || got.Match == nil || got.Match.ID != "first" {
+		t.Errorf("Wanted the first signature to win, got %+v", got)
+	}
+}
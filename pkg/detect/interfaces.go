@@ -0,0 +1,137 @@
+package detect
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Kind tells what kind of declaration a FunctionDetails describes.
+type Kind string
+
+const (
+	// KindFunction is a function or a method that matches a
+	// FunctionSignature.
+	KindFunction Kind = "function"
+	// KindType is a named type whose methods implement an
+	// InterfaceSignature.
+	KindType Kind = "type"
+	// KindVariable is a package level variable holding a function that
+	// matches a FunctionSignature.
+	KindVariable Kind = "variable"
+	// KindFactory is a function returning a function that matches a
+	// FunctionSignature, see WithFactories.
+	KindFactory Kind = "factory"
+)
+
+// InterfaceSignature describes an interface by its methods. For example
+// http.Handler is:
+//
+//	InterfaceSignature{Methods: []MethodSignature{{
+//		Name: "ServeHTTP",
+//		FunctionSignature: FunctionSignature{In: []FunctionArg{
+//			{ImportPath: "net/http", Name: "ResponseWriter"},
+//			{ImportPath: "net/http", Name: "Request", Pointer: true},
+//		}},
+//	}}}
+type InterfaceSignature struct {
+	SignatureInfo
+	Methods []MethodSignature `json:"methods" toml:"methods"`
+}
+
+// MethodSignature is a single named method of an InterfaceSignature. The
+// Receiver of the FunctionSignature is ignored.
+type MethodSignature struct {
+	Name string `json:"name" toml:"name"`
+	FunctionSignature
+}
+
+func (is *InterfaceSignature) String() string {
+	methods := make([]string, 0, len(is.Methods))
+	for _, m := range is.Methods {
+		methods = append(methods, m.Name+strings.TrimPrefix(m.FunctionSignature.String(), "func"))
+	}
+	if len(methods) == 0 {
+		return "interface{}"
+	}
+	return "interface{ " + strings.Join(methods, "; ") + " }"
+}
+
+// method is a method declared in the scanned source.
+type method struct {
+	name    string
+	pointer bool
+	sig     FunctionSignature
+}
+
+// fileMethods adds the methods declared in astFile to methods, keyed by the
+// name of the receiver type.
+func fileMethods(resolver *argResolver, astFile *ast.File, methods map[string][]method) {
+	for _, decl := range astFile.Decls {
+		f, ok := decl.(*ast.FuncDecl)
+		if !ok || f.Recv == nil {
+			continue
+		}
+		recv, pointer := receiverType(f.Recv)
+		if recv == "" {
+			continue
+		}
+		methods[recv] = append(methods[recv], method{name: f.Name.Name, pointer: pointer, sig: resolver.signature(f.Type)})
+	}
+}
+
+// checkType returns the details of the interface in ifaces with the highest
+// priority that the type declared by spec implements, or nil if it
+// implements none of them.
+// Only the methods declared on the type itself count, methods promoted from
+// embedded fields are not considered. Unexported, generic and alias types
+// never match. If some of the methods have pointer receivers, only a pointer
+// to the type implements the interface, which is reported with
+// PointerReceiver.
+func (d *Detector) checkType(fset *token.FileSet, spec *ast.TypeSpec, ifaces []InterfaceSignature, methods map[string][]method) *FunctionDetails {
+	if !spec.Name.IsExported() || spec.TypeParams != nil || spec.Assign.IsValid() {
+		return nil
+	}
+	for _, i := range d.ifaceOrder {
+		pointer, ok := d.implements(ifaces[i], methods[spec.Name.Name])
+		if !ok {
+			continue
+		}
+		iface := d.interfaces[i]
+		return &FunctionDetails{
+			Kind:            KindType,
+			Name:            spec.Name.Name,
+			Signature:       iface.String(),
+			Position:        fset.Position(spec.Name.Pos()),
+			PointerReceiver: pointer,
+			Interface:       &iface,
+		}
+	}
+	return nil
+}
+
+// implements reports whether methods has a matching method for every method
+// of iface and whether any of those have a pointer receiver.
+func (d *Detector) implements(iface InterfaceSignature, methods []method) (bool, bool) {
+	if len(iface.Methods) == 0 {
+		return false, false
+	}
+	pointer := false
+	for _, want := range iface.Methods {
+		found := false
+		for _, m := range methods {
+			if m.name != want.Name {
+				continue
+			}
+			if _, ok := d.signatureMatches(want.FunctionSignature, m.sig); ok {
+				found = true
+				pointer = pointer || m.pointer
+			}
+			break
+		}
+		if !found {
+			return false, false
+		}
+	}
+	return pointer, true
+}
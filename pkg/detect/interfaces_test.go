@@ -0,0 +1,106 @@
+package detect
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+const interfacesDir = "./testdata/interfaces"
+
+const handlerInterface = `
+interfaces:
+  - methods:
+      - name: ServeHTTP
+        in:
+          - importPath: net/http
+            name: ResponseWriter
+          - importPath: net/http
+            name: Request
+            pointer: true
+`
+
+func TestInterfaces(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"syntax":      nil,
+		"typechecked": {WithTypeChecking()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d, err := NewDetectorFromString(handlerInterface, opts...)
+			if err != nil {
+				t.Fatalf("Failed to read interfaces: %s", err)
+			}
+			p, err := d.ScanPackage(interfacesDir)
+			if err != nil {
+				t.Fatalf("Failed to scan package: %s", err)
+			}
+			// Other has a ServeHTTP with the wrong arguments and hidden is
+			// not exported.
+			want := []FunctionDetails{
+				{Kind: KindType, Name: "Handler", PointerReceiver: true, Constructor: "NewHandler"},
+				{Kind: KindType, Name: "Value"},
+			}
+			if len(p.Matches) != len(want) {
+				t.Fatalf("Wanted %v, got %v", want, p.Matches)
+			}
+			for i := range want {
+				got := p.Matches[i]
+				if got.Kind != want[i].Kind || got.Name != want[i].Name ||
+					got.PointerReceiver != want[i].PointerReceiver || got.Constructor != want[i].Constructor {
+					t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got)
+				}
+				if got.Signature != "interface{ ServeHTTP(http.ResponseWriter, *http.Request) }" {
+					t.Errorf("Error at %d, unexpected signature %q", i, got.Signature)
+				}
+			}
+		})
+	}
+}
+
+func TestInterfacesAndFunctions(t *testing.T) {
+	handler := httpSignature("")
+	d := NewDetector([]FunctionSignature{handler}, WithInterfaces(InterfaceSignature{
+		Methods: []MethodSignature{{Name: "ServeHTTP", FunctionSignature: handler}},
+	}))
+	p, err := d.ScanPackage(interfacesDir)
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	// Both the types and their methods are reported, in declaration order.
+	want := []struct {
+		kind Kind
+		name string
+	}{
+		{KindType, "Handler"},
+		{KindFunction, "ServeHTTP"},
+		{KindFunction, "ServeHTTP"},
+		{KindFunction, "ServeHTTP"},
+		{KindType, "Value"},
+	}
+	if len(p.Matches) != len(want) {
+		t.Fatalf("Wanted %v, got %v", want, p.Matches)
+	}
+	for i := range want {
+		if p.Matches[i].Kind != want[i].kind || p.Matches[i].Name != want[i].name {
+			t.Errorf("Error at %d, wanted %v, got %+v", i, want[i], p.Matches[i])
+		}
+	}
+}
+
+func TestInterfacesTOML(t *testing.T) {
+	want := FunctionSignatures{Interfaces: []InterfaceSignature{{
+		Methods: []MethodSignature{{Name: "ServeHTTP", FunctionSignature: httpSignature("")}},
+	}}}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("Failed to encode interfaces: %s", err)
+	}
+	d, err := NewDetectorFromString(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to decode interfaces: %s\n%s", err, buf.String())
+	}
+	if got, want := d.Signatures(), want.Interfaces[0].String()+"\n"; got != want {
+		t.Errorf("Wanted %q, got %q", want, got)
+	}
+}
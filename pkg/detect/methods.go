@@ -0,0 +1,118 @@
+package detect
+
+import (
+	"go/ast"
+)
+
+// ReceiverMode controls whether methods match a signature.
+type ReceiverMode string
+
+const (
+	// ReceiverRequired only matches methods.
+	ReceiverRequired ReceiverMode = "REQUIRED"
+	// ReceiverForbidden only matches functions.
+	ReceiverForbidden ReceiverMode = "FORBIDDEN"
+)
+
+// receiverType returns the name of the receiver type and whether it's a
+// pointer, or "" if recv is nil. For generic types the type parameters are
+// left out, so "func (s *Server[T]) ..." returns "Server".
+func receiverType(recv *ast.FieldList) (string, bool) {
+	if recv == nil || len(recv.List) == 0 {
+		return "", false
+	}
+	t := recv.List[0].Type
+	pointer := false
+	if star, ok := t.(*ast.StarExpr); ok {
+		pointer = true
+		t = star.X
+	}
+	switch e := t.(type) {
+	case *ast.IndexExpr:
+		t = e.X
+	case *ast.IndexListExpr:
+		t = e.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name, pointer
+	}
+	return "", pointer
+}
+
+// constructor is a function that creates an instance of a type.
+type constructor struct {
+	name string
+	// err is set if the constructor also returns an error.
+	err bool
+}
+
+// findConstructors returns the functions that can be used to create an
+// instance of the types declared in files, keyed by type name. A constructor
+// is an exported function without a receiver or parameters that returns the
+// type (or a pointer to it), optionally followed by an error, for example:
+// func NewServer() *Server
+// func NewServer() (Server, error)
+// If there are multiple candidates, the one named New<type> wins, otherwise
+// the first one.
+func findConstructors(files []*ast.File) map[string]constructor {
+	constructors := make(map[string]constructor)
+	for _, astFile := range files {
+		for _, decl := range astFile.Decls {
+			f, ok := decl.(*ast.FuncDecl)
+			if !ok || f.Recv != nil || !f.Name.IsExported() || f.Type.TypeParams != nil {
+				continue
+			}
+			if f.Type.Params != nil && len(f.Type.Params.List) > 0 {
+				continue
+			}
+			typeName, withErr := constructedType(f.Type.Results)
+			if typeName == "" {
+				continue
+			}
+			if existing, ok := constructors[typeName]; !ok || existing.name != "New"+typeName && f.Name.Name == "New"+typeName {
+				constructors[typeName] = constructor{name: f.Name.Name, err: withErr}
+			}
+		}
+	}
+	return constructors
+}
+
+// constructedType returns the name of the type that results (T, *T, (T, error)
+// or (*T, error)) constructs or "" if it's not one of those, and whether an
+// error is returned as well.
+func constructedType(results *ast.FieldList) (string, bool) {
+	if results == nil {
+		return "", false
+	}
+	var types []ast.Expr
+	for _, r := range results.List {
+		types = append(types, expandFieldTypes(r)...)
+	}
+	if len(types) == 2 {
+		if id, ok := types[1].(*ast.Ident); !ok || id.Name != "error" {
+			return "", false
+		}
+	} else if len(types) != 1 {
+		return "", false
+	}
+	t := types[0]
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name, len(types) == 2
+	}
+	return "", false
+}
+
+// expandFieldTypes returns the type of the field once for each of its names.
+func expandFieldTypes(field *ast.Field) []ast.Expr {
+	if len(field.Names) == 0 {
+		return []ast.Expr{field.Type}
+	}
+	types := make([]ast.Expr, 0, len(field.Names))
+	for range field.Names {
+		types = append(types, field.Type)
+	}
+	return types
+}
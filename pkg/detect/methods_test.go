@@ -0,0 +1,70 @@
+package detect
+
+import (
+	"testing"
+)
+
+const methodsDir = "./testdata/methods"
+
+func httpSignature(receiver ReceiverMode) FunctionSignature {
+	return FunctionSignature{
+		Receiver: receiver,
+		In: []FunctionArg{
+			{ImportPath: "net/http", Name: "ResponseWriter"},
+			{ImportPath: "net/http", Name: "Request", Pointer: true},
+		},
+	}
+}
+
+func TestReceivers(t *testing.T) {
+	d := NewDetector([]FunctionSignature{httpSignature("")})
+	p, err := d.ScanPackage(methodsDir)
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	want := []FunctionDetails{
+		{Name: "Handle", Receiver: "Handler", Constructor: "NewHandler", ConstructorError: true},
+		{Name: "Receive", Receiver: "Server", PointerReceiver: true, Constructor: "NewServer"},
+	}
+	if len(p.Matches) != len(want) {
+		t.Fatalf("Wanted %v, got %v", want, p.Matches)
+	}
+	for i := range want {
+		got := p.Matches[i]
+		if got.Name != want[i].Name || got.Receiver != want[i].Receiver ||
+			got.PointerReceiver != want[i].PointerReceiver || got.Constructor != want[i].Constructor ||
+			got.ConstructorError != want[i].ConstructorError {
+			t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got)
+		}
+	}
+}
+
+func TestReceiverMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode        ReceiverMode
+		wantFile    bool
+		wantMethods int
+	}{
+		{mode: "", wantFile: true, wantMethods: 2},
+		{mode: ReceiverRequired, wantFile: false, wantMethods: 2},
+		{mode: ReceiverForbidden, wantFile: true, wantMethods: 0},
+	} {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			d := NewDetector([]FunctionSignature{httpSignature(tc.mode)})
+			got, err := d.ReadAndCheckFile("./testdata/f1.go")
+			if err != nil {
+				t.Fatalf("Failed to check file: %s", err)
+			}
+			if tc.wantFile != (got != nil) {
+				t.Errorf("Wanted a match for a function %v, got %+v", tc.wantFile, got)
+			}
+			p, err := d.ScanPackage(methodsDir)
+			if err != nil {
+				t.Fatalf("Failed to scan package: %s", err)
+			}
+			if len(p.Matches) != tc.wantMethods {
+				t.Errorf("Wanted %d methods, got %+v", tc.wantMethods, p.Matches)
+			}
+		})
+	}
+}
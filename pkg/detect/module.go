@@ -0,0 +1,141 @@
+package detect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ErrNoModule is returned by FindModule if there's no go.mod in the
+// directory or any of its parents.
+var ErrNoModule = errors.New("no go.mod found")
+
+// Module is the Go module a directory belongs to.
+type Module struct {
+	// Path is the module path, for example github.com/vaikas/gofunctypechecker.
+	Path string
+	// Dir is the absolute path of the directory with the go.mod.
+	Dir string
+	// Workspace is the go.work file of the workspace the module is used
+	// by, if there is one.
+	Workspace string
+}
+
+// FindModule returns the module that dir belongs to, which is the one with
+// the closest go.mod in dir or any of its parents. Just like for the go
+// tool, if there's a go.work in dir or any of its parents (or GOWORK points
+// to one) the module must be one of the modules it uses. GOWORK=off turns
+// workspaces off.
+func FindModule(dir string) (*Module, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	modDir, modPath, err := findGoMod(abs)
+	if err != nil {
+		return nil, err
+	}
+	if modDir == "" {
+		return nil, fmt.Errorf("%w in %s or any of its parents", ErrNoModule, dir)
+	}
+	m := &Module{Path: modPath, Dir: modDir}
+
+	workFile, err := findGoWork(abs)
+	if err != nil || workFile == "" {
+		return m, err
+	}
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, err
+	}
+	work, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, use := range work.Use {
+		useDir := use.Path
+		if !filepath.IsAbs(useDir) {
+			useDir = filepath.Join(filepath.Dir(workFile), useDir)
+		}
+		if filepath.Clean(useDir) == modDir {
+			m.Workspace = workFile
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("module %s in %s is not one of the modules used by %s", modPath, modDir, workFile)
+}
+
+// ImportPath returns the import path of the package in dir, which must be
+// part of the module.
+func (m *Module) ImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(m.Dir, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not part of module %s in %s", dir, m.Path, m.Dir)
+	}
+	return path.Join(m.Path, filepath.ToSlash(rel)), nil
+}
+
+// findGoMod returns the directory of the closest go.mod in the absolute
+// directory dir or any of its parents along with its module path. If
+// there's no go.mod it returns an empty directory.
+func findGoMod(dir string) (string, string, error) {
+	for modDir := dir; ; {
+		goMod := filepath.Join(modDir, "go.mod")
+		data, err := os.ReadFile(goMod)
+		if err == nil {
+			f, err := modfile.ParseLax(goMod, data, nil)
+			if err != nil {
+				return "", "", err
+			}
+			if f.Module == nil || f.Module.Mod.Path == "" {
+				return "", "", errors.New("no module declaration in " + goMod)
+			}
+			return modDir, f.Module.Mod.Path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+		parent := filepath.Dir(modDir)
+		if parent == modDir {
+			return "", "", nil
+		}
+		modDir = parent
+	}
+}
+
+// findGoWork returns the go.work that applies to the absolute directory
+// dir, or an empty string if there is none.
+func findGoWork(dir string) (string, error) {
+	switch gowork := os.Getenv("GOWORK"); {
+	case gowork == "off":
+		return "", nil
+	case gowork != "":
+		return filepath.Abs(gowork)
+	}
+	for workDir := dir; ; {
+		goWork := filepath.Join(workDir, "go.work")
+		if _, err := os.Stat(goWork); err == nil {
+			return goWork, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(workDir)
+		if parent == workDir {
+			return "", nil
+		}
+		workDir = parent
+	}
+}
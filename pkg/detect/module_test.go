@@ -0,0 +1,89 @@
+package detect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files, given relative to dir, with their content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindModule(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/go.mod":             "// The app.\nmodule\t\"example.com/app\" // quoted\n\ngo 1.22\n",
+		"app/pkg/fn/fn.go":       "package fn\n",
+		"app/nested/go.mod":      "module example.com/nested\n",
+		"app/nested/sub/sub.go":  "package sub\n",
+		"nomodule/main.go":       "package main\n",
+		"broken/go.mod":          "go 1.22\n",
+		"work/go.work":           "go 1.22\n\nuse ./used\n",
+		"work/used/go.mod":       "module example.com/used\n",
+		"work/unused/go.mod":     "module example.com/unused\n",
+		"work/used/handler/h.go": "package handler\n",
+	})
+
+	tests := []struct {
+		dir        string
+		importPath string
+		workspace  bool
+		err        string
+	}{
+		{dir: "app", importPath: "example.com/app"},
+		{dir: "app/pkg/fn", importPath: "example.com/app/pkg/fn"},
+		{dir: "app/nested/sub", importPath: "example.com/nested/sub"},
+		{dir: "work/used/handler", importPath: "example.com/used/handler", workspace: true},
+		{dir: "work/unused", err: "not one of the modules used by"},
+		{dir: "broken", err: "no module declaration"},
+	}
+	for _, test := range tests {
+		t.Run(test.dir, func(t *testing.T) {
+			pkgDir := filepath.Join(dir, test.dir)
+			m, err := FindModule(pkgDir)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Wanted error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to find module: %s", err)
+			}
+			importPath, err := m.ImportPath(pkgDir)
+			if err != nil {
+				t.Fatalf("Failed to get import path: %s", err)
+			}
+			if importPath != test.importPath {
+				t.Errorf("Wanted %q, got %q", test.importPath, importPath)
+			}
+			if (m.Workspace != "") != test.workspace {
+				t.Errorf("Wanted workspace %v, got %q", test.workspace, m.Workspace)
+			}
+		})
+	}
+
+	if _, err := FindModule(filepath.Join(dir, "nomodule")); !errors.Is(err, ErrNoModule) {
+		t.Errorf("Wanted ErrNoModule, got %v", err)
+	}
+
+	// Without workspaces any module goes.
+	t.Setenv("GOWORK", "off")
+	if _, err := FindModule(filepath.Join(dir, "work/unused")); err != nil {
+		t.Errorf("Wanted no error with GOWORK=off, got %s", err)
+	}
+}
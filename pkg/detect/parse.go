@@ -0,0 +1,136 @@
+package detect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// ParseSignature parses a signature written in Go syntax, the same way
+// FunctionSignature.String writes it. For example:
+// func(context.Context, cloudevents.Event) (*cloudevents.Event, error)
+// imports maps the package names used in the signature to their import
+// paths, for example "cloudevents" to "github.com/cloudevents/sdk-go/v2".
+// Predeclared types like error don't need one. An _ type is a wildcard, so
+// "*_" is any pointer and "api._" any type of the package imported as api.
+func ParseSignature(s string, imports map[string]string) (FunctionSignature, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "func") {
+		return FunctionSignature{}, fmt.Errorf("signature %q does not start with func", s)
+	}
+	// Function types can't have type parameters, so parse it as a function
+	// declaration instead.
+	src := "package p\nfunc _" + strings.TrimPrefix(s, "func")
+	astFile, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return FunctionSignature{}, fmt.Errorf("failed to parse signature %q: %w", s, err)
+	}
+	if len(astFile.Decls) != 1 {
+		return FunctionSignature{}, fmt.Errorf("signature %q is not a single function", s)
+	}
+	f, ok := astFile.Decls[0].(*ast.FuncDecl)
+	if !ok || f.Name.Name != "_" || f.Body != nil {
+		return FunctionSignature{}, fmt.Errorf("signature %q is not a single function", s)
+	}
+
+	var unknown []string
+	ast.Inspect(f.Type, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && imports[id.Name] == "" {
+				unknown = append(unknown, id.Name)
+			}
+		}
+		return true
+	})
+	if len(unknown) > 0 {
+		return FunctionSignature{}, fmt.Errorf("signature %q uses packages without an import: %s", s, strings.Join(unknown, ", "))
+	}
+
+	resolver := &argResolver{imports: imports}
+	fs := resolver.signature(f.Type)
+	if err := fs.fromSyntax(); err != nil {
+		return FunctionSignature{}, fmt.Errorf("signature %q: %w", s, err)
+	}
+	return fs, nil
+}
+
+// fromSyntax turns the _ types into wildcards and the _ names into unnamed
+// arguments, since that's how String writes them. It returns an error if
+// any of the types can't be expressed as a FunctionArg.
+func (fs *FunctionSignature) fromSyntax() error {
+	for i := range fs.TypeParams {
+		if err := fs.TypeParams[i].Constraint.fromSyntax(); err != nil {
+			return fmt.Errorf("constraint of %s: %w", fs.TypeParams[i].Name, err)
+		}
+	}
+	for i := range fs.In {
+		if err := fs.In[i].fromSyntax(); err != nil {
+			return fmt.Errorf("arg %d: %w", i+1, err)
+		}
+	}
+	for i := range fs.Out {
+		if err := fs.Out[i].fromSyntax(); err != nil {
+			return fmt.Errorf("result %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (fa *FunctionArg) fromSyntax() error {
+	if fa.ParamName == "_" {
+		fa.ParamName = ""
+	}
+	if fa.Name == "_" {
+		fa.Name = Wildcard
+	}
+	for _, elem := range []*FunctionArg{fa.Key, fa.Elem} {
+		if elem != nil {
+			if err := elem.fromSyntax(); err != nil {
+				return err
+			}
+		}
+	}
+	if fa.Func != nil {
+		return fa.Func.fromSyntax()
+	}
+	// typeToFunctionArg returns an empty FunctionArg for what it can't map.
+	if fa.Name == "" && !fa.Slice && fa.ArrayLen == 0 && fa.Key == nil {
+		return ErrUnsupportedType
+	}
+	return nil
+}
+
+// parseSignatures fills in the signatures (and methods of the interfaces)
+// that are given as a Signature string, using the Imports.
+func (fs *FunctionSignatures) parseSignatures() error {
+	for i := range fs.FunctionSignatures {
+		if err := fs.FunctionSignatures[i].parse(fs.Imports); err != nil {
+			return err
+		}
+	}
+	for i := range fs.Interfaces {
+		for j := range fs.Interfaces[i].Methods {
+			if err := fs.Interfaces[i].Methods[j].parse(fs.Imports); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *FunctionSignature) parse(imports map[string]string) error {
+	if fs.Signature == "" {
+		return nil
+	}
+	if len(fs.TypeParams) > 0 || len(fs.In) > 0 || len(fs.Out) > 0 {
+		return fmt.Errorf("signature %q can not be combined with typeParams, in or out", fs.Signature)
+	}
+	parsed, err := ParseSignature(fs.Signature, imports)
+	if err != nil {
+		return err
+	}
+	fs.TypeParams, fs.In, fs.Out = parsed.TypeParams, parsed.In, parsed.Out
+	return nil
+}
@@ -0,0 +1,130 @@
+package detect
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var parseImports = map[string]string{
+	"context": "context",
+	"http":    "net/http",
+	"v2":      "github.com/cloudevents/sdk-go/v2",
+	"proto":   "github.com/mattmoor/korpc-sample/gen/proto",
+	"api":     "example.com/api",
+	"other":   "example.com/other",
+}
+
+// sameSignature reports whether a and b are the same, apart from the
+// Signature string and nil vs empty lists.
+func sameSignature(t *testing.T, a, b FunctionSignature) bool {
+	a.Signature, b.Signature = "", ""
+	ja, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("Failed to encode %+v: %s", a, err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Failed to encode %+v: %s", b, err)
+	}
+	return string(ja) == string(jb)
+}
+
+func TestParseSignatureRoundTrip(t *testing.T) {
+	sigs := append([]FunctionSignature{}, validFunctions...)
+	d, err := NewDetectorFromString(wildcardSignatures)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures: %s", err)
+	}
+	sigs = append(sigs, d.sigs...)
+	sigs = append(sigs, FunctionSignature{
+		In: []FunctionArg{
+			{Name: "int", ParamName: "a"},
+			{ImportPath: "context", Name: "Context"},
+			{Channel: Send, Name: "string", Pointer: true},
+			{ArrayLen: 4, Elem: &FunctionArg{Name: "byte"}},
+			{Name: "string", Variadic: true, ParamName: "rest"},
+		},
+		Out: []FunctionArg{{Name: "error", ParamName: "err"}},
+	})
+	for _, sig := range sigs {
+		s := sig.String()
+		got, err := ParseSignature(s, parseImports)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", s, err)
+			continue
+		}
+		if !sameSignature(t, got, sig) {
+			t.Errorf("Parsing %q, wanted %+v, got %+v", s, sig, got)
+		}
+		if got.String() != s {
+			t.Errorf("Wanted %q, got %q", s, got.String())
+		}
+	}
+}
+
+func TestParseSignatureErrors(t *testing.T) {
+	for _, tc := range []struct {
+		sig  string
+		want string
+	}{
+		{sig: "(http.ResponseWriter)", want: "does not start with func"},
+		{sig: "func(", want: "failed to parse"},
+		{sig: "func() {}", want: "not a single function"},
+		{sig: "func(); var x int", want: "not a single function"},
+		{sig: "func(foo.Bar, baz.Qux)", want: "without an import: foo, baz"},
+		{sig: "func(**int)", want: "arg 1: unsupported type"},
+		{sig: "func() [n]int", want: "result 1: unsupported type"},
+	} {
+		_, err := ParseSignature(tc.sig, parseImports)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parsing %q, wanted error containing %q, got %v", tc.sig, tc.want, err)
+		}
+	}
+}
+
+const goSyntaxSignatures = `
+imports:
+  http: net/http
+  cloudevents: github.com/cloudevents/sdk-go/v2
+functionSignatures:
+  - signature: func(http.ResponseWriter, *http.Request)
+  - signature: func(cloudevents.Event) (*cloudevents.Event, error)
+    receiver: FORBIDDEN
+interfaces:
+  - methods:
+      - name: ServeHTTP
+        signature: func(http.ResponseWriter, *http.Request)
+`
+
+func TestSignaturesInGoSyntax(t *testing.T) {
+	d, err := NewDetectorFromString(goSyntaxSignatures)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures: %s", err)
+	}
+	want := []FunctionSignature{validFunctions[0], {
+		Receiver: ReceiverForbidden,
+		In:       []FunctionArg{{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event"}},
+		Out: []FunctionArg{
+			{ImportPath: "github.com/cloudevents/sdk-go/v2", Name: "Event", Pointer: true},
+			{Name: "error"},
+		},
+	}}
+	if len(d.sigs) != len(want) {
+		t.Fatalf("Wanted %d signatures, got %d", len(want), len(d.sigs))
+	}
+	for i := range want {
+		got := d.sigs[i]
+		if !sameSignature(t, got, want[i]) {
+			t.Errorf("Error at %d, wanted %+v, got %+v", i, want[i], got)
+		}
+	}
+	if len(d.interfaces) != 1 || !reflect.DeepEqual(d.interfaces[0].Methods[0].In, validFunctions[0].In) {
+		t.Errorf("Wanted the ServeHTTP interface, got %+v", d.interfaces)
+	}
+
+	if _, err := NewDetectorFromString("functionSignatures:\n  - signature: func(http.Request)\n"); err == nil {
+		t.Error("Expected an error for a signature without imports")
+	}
+}
@@ -0,0 +1,207 @@
+package detect
+
+import (
+	"errors"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Package holds the results of scanning a single Go package.
+type Package struct {
+	// Dir is the directory the package was read from.
+	Dir string
+	// ImportPath is the import path of the package, for example
+	// github.com/vaikas/gofunctypechecker/pkg/detect. It's empty if the
+	// package is not part of a module.
+	ImportPath string
+	// Name is the name of the package as declared in the source.
+	Name string
+	// Matches are all the functions in the package that match one of the
+	// signatures, in file order.
+	Matches []FunctionDetails
+	// Diagnostics explain why the functions that came close to one of the
+	// signatures don't match, in file order.
+	Diagnostics []Diagnostic
+	// Errors are the problems with individual declarations that didn't stop
+	// the scan, like an *UnsupportedTypeError for each of the arguments
+	// of a function that can't be checked.
+	Errors []error
+}
+
+// Check returns the only function of the package that matches one of the
+// signatures. If there are none it returns nil, if there are more than one
+// it returns the first one and an *AmbiguityError listing all of them, even
+// if they are in different files.
+func (p *Package) Check() (*FunctionDetails, error) {
+	return unique(p.Matches)
+}
+
+// ScanPackage parses all the Go files of the package in dir together and
+// returns all the functions that match one of the signatures. Files that are
+// excluded by build constraints (and test files) are not scanned.
+func (d *Detector) ScanPackage(dir string) (*Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	importPath, err := packageImportPath(bp, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		astFile, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, astFile)
+	}
+
+	typeCheckPath := importPath
+	if typeCheckPath == "" {
+		typeCheckPath = bp.Name
+	}
+	p := d.scanFiles(fset, typeCheckPath, dir, files, nil)
+	p.ImportPath = importPath
+	for i := range p.Matches {
+		p.Matches[i].Package = importPath
+	}
+	return p, nil
+}
+
+// ScanFile is like ScanPackage, but only for the single file, regardless of
+// its build constraints. The import path is the one of the directory of the
+// file, or empty if it's not part of a module. Unlike AllFromFile it returns
+// the near misses and the functions using unsupported types as well.
+func (d *Detector) ScanFile(filename string) (*Package, error) {
+	src, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	importPath, err := importPathForDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	typeCheckPath := importPath
+	if typeCheckPath == "" {
+		typeCheckPath = astFile.Name.Name
+	}
+	p := d.scanFiles(fset, typeCheckPath, dir, []*ast.File{astFile}, nil)
+	p.ImportPath = importPath
+	for i := range p.Matches {
+		p.Matches[i].Package = importPath
+	}
+	return p, nil
+}
+
+// ScanFiles is like ScanPackage, but for the already parsed files of the
+// package with the import path importPath in dir. If info is not nil it's
+// used as the type information of the package, which is useful when the
+// files have already been type checked, for example by an analyzer. Test
+// files are not skipped, it's up to the caller to leave them out.
+func (d *Detector) ScanFiles(fset *token.FileSet, importPath, dir string, files []*ast.File, info *types.Info) *Package {
+	p := d.scanFiles(fset, importPath, dir, files, info)
+	for i := range p.Matches {
+		p.Matches[i].Package = importPath
+	}
+	return p
+}
+
+func (d *Detector) scanFiles(fset *token.FileSet, path, dir string, files []*ast.File, info *types.Info) *Package {
+	p := &Package{Dir: dir, ImportPath: path}
+	if len(files) > 0 {
+		p.Name = files[0].Name.Name
+	}
+	p.Matches, p.Diagnostics, p.Errors = d.detect(fset, path, dir, files, info)
+	return p
+}
+
+// CheckPackage is like CheckFile, but for all the files of the package in
+// dir. See Package.Check for details.
+func (d *Detector) CheckPackage(dir string) (*FunctionDetails, error) {
+	p, err := d.ScanPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	return p.Check()
+}
+
+// ScanModule scans every package in the directory tree rooted at root, the
+// same way ScanPackage does. Just like the go tool, directories named
+// testdata or vendor, directories starting with . or _ and nested modules
+// are skipped. Directories without any Go files are left out of the results.
+func (d *Detector) ScanModule(root string) ([]*Package, error) {
+	var pkgs []*Package
+	err := filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if dir != root {
+			name := entry.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		p, err := d.ScanPackage(dir)
+		if err != nil {
+			var noGo *build.NoGoError
+			if errors.As(err, &noGo) {
+				return nil
+			}
+			return err
+		}
+		pkgs = append(pkgs, p)
+		return nil
+	})
+	return pkgs, err
+}
+
+// packageImportPath returns the import path of the package bp in dir. The
+// packages of the standard library have theirs in bp, the go.mod in GOROOT
+// is for module std which is not part of their import paths. For the others
+// see importPathForDir.
+func packageImportPath(bp *build.Package, dir string) (string, error) {
+	if bp.Goroot {
+		return bp.ImportPath, nil
+	}
+	return importPathForDir(dir)
+}
+
+// importPathForDir returns the import path of the package in dir based on
+// the closest go.mod in dir or any of its parents. If there's no go.mod it
+// returns an empty import path. Unlike FindModule it doesn't care about
+// workspaces.
+func importPathForDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	modDir, modPath, err := findGoMod(abs)
+	if err != nil || modDir == "" {
+		return "", err
+	}
+	m := &Module{Path: modPath, Dir: modDir}
+	return m.ImportPath(abs)
+}
@@ -0,0 +1,103 @@
+package detect
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const scanDir = "./testdata/scan"
+const scanImportPath = "github.com/vaikas/gofunctypechecker/pkg/detect/testdata/scan"
+
+func TestScanPackage(t *testing.T) {
+	d, err := NewDetectorFromFile(signatureFileYAML)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileYAML, err)
+	}
+	p, err := d.ScanPackage(scanDir)
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	if p.ImportPath != scanImportPath {
+		t.Errorf("ImportPath differs got %q expected %q", p.ImportPath, scanImportPath)
+	}
+	if p.Name != "scan" {
+		t.Errorf("Name differs got %q expected %q", p.Name, "scan")
+	}
+
+	// ignored.go is excluded by build constraints and http_test.go is a test.
+	want := []FunctionDetails{
+		{
+			Name:      "ReceiveEvent",
+			Package:   scanImportPath,
+			Signature: "func(v2.Event) (*v2.Event, error)",
+		},
+		{
+			Name:      "ReceiveHTTP",
+			Package:   scanImportPath,
+			Signature: "func(http.ResponseWriter, *http.Request)",
+		},
+	}
+	wantFiles := []string{"event.go", "http.go"}
+	wantLines := []int{7, 8}
+	if len(p.Matches) != len(want) {
+		t.Fatalf("Wanted %v, got %v", want, p.Matches)
+	}
+	for i := range want {
+		got := p.Matches[i]
+		if got.Name != want[i].Name || got.Package != want[i].Package || got.Signature != want[i].Signature {
+			t.Errorf("Error at %d, wanted %v, got %v", i, want[i], got)
+		}
+		if filepath.Base(got.Position.Filename) != wantFiles[i] || got.Position.Line != wantLines[i] {
+			t.Errorf("Error at %d, wanted position %s:%d, got %s", i, wantFiles[i], wantLines[i], got.Position)
+		}
+	}
+
+	// The matches are in different files, but that's still ambiguous.
+	_, err = p.Check()
+	var ambiguous *AmbiguityError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Expected an AmbiguityError, got %v", err)
+	}
+	if len(ambiguous.Matches) != 2 {
+		t.Errorf("Expected 2 ambiguous matches, got %v", ambiguous.Matches)
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	d, err := NewDetectorFromFile(signatureFileYAML, WithTypeChecking())
+	if err != nil {
+		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileYAML, err)
+	}
+	got, err := d.CheckPackage(filepath.Join(scanDir, "sub"))
+	if err != nil {
+		t.Fatalf("Failed to check package: %s", err)
+	}
+	if got == nil {
+		t.Fatal("Expected a match, got nil")
+	}
+	if got.Name != "Receive" || got.Package != scanImportPath+"/sub" {
+		t.Errorf("Wanted Receive in %s, got %+v", scanImportPath+"/sub", got)
+	}
+}
+
+func TestScanModule(t *testing.T) {
+	d, err := NewDetectorFromFile(signatureFileYAML)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures from file: %q : %s ", signatureFileYAML, err)
+	}
+	pkgs, err := d.ScanModule(scanDir)
+	if err != nil {
+		t.Fatalf("Failed to scan module: %s", err)
+	}
+	// nested has a go.mod of its own, so it's not part of the module.
+	want := []string{scanImportPath, scanImportPath + "/sub"}
+	if len(pkgs) != len(want) {
+		t.Fatalf("Wanted packages %v, got %d packages", want, len(pkgs))
+	}
+	for i := range want {
+		if pkgs[i].ImportPath != want[i] {
+			t.Errorf("Error at %d, wanted %q, got %q", i, want[i], pkgs[i].ImportPath)
+		}
+	}
+}
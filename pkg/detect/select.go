@@ -0,0 +1,137 @@
+package detect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelectionPolicy decides which of the matches of a package is the one to
+// use, see Package.Select.
+type SelectionPolicy string
+
+const (
+	// SelectExact selects the function with the given name, which must
+	// match one of the signatures.
+	SelectExact SelectionPolicy = "exact"
+	// SelectUnique selects the only match of the package, there must be
+	// exactly one.
+	SelectUnique SelectionPolicy = "unique"
+	// SelectFirstByPriority selects the match of the signature (or
+	// interface) with the highest priority. If several matches have the
+	// same priority the first one in file order wins.
+	SelectFirstByPriority SelectionPolicy = "first-by-priority"
+)
+
+// ParseSelectionPolicy returns the SelectionPolicy called s.
+func ParseSelectionPolicy(s string) (SelectionPolicy, error) {
+	switch p := SelectionPolicy(s); p {
+	case SelectExact, SelectUnique, SelectFirstByPriority:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown selection policy %q, expected one of %s, %s or %s", s, SelectExact, SelectUnique, SelectFirstByPriority)
+}
+
+// NoMatchError is returned by Package.Select if none of the matches can be
+// selected.
+type NoMatchError struct {
+	// Name is the name of the function that was asked for, if any.
+	Name string
+	// Candidates are the matches of the package, which don't have that
+	// name.
+	Candidates []FunctionDetails
+	// Diagnostic explains why the function with the name doesn't match, if
+	// it came close.
+	Diagnostic *Diagnostic
+}
+
+func (e *NoMatchError) Error() string {
+	switch {
+	case e.Diagnostic != nil:
+		return fmt.Sprintf("function %s does not match %s: %s", e.Name, e.Diagnostic.Signature, strings.Join(e.Diagnostic.Reasons, ", "))
+	case e.Name != "" && len(e.Candidates) > 0:
+		return fmt.Sprintf("function %s does not match any signature, the matching functions are: %s", e.Name, candidates(e.Candidates))
+	case e.Name != "":
+		return fmt.Sprintf("function %s does not match any signature", e.Name)
+	}
+	return "no function matches any signature"
+}
+
+// candidates lists the names and positions of the matches.
+func candidates(matches []FunctionDetails) string {
+	list := make([]string, 0, len(matches))
+	for _, m := range matches {
+		list = append(list, fmt.Sprintf("%s (%s)", m.Name, m.Position))
+	}
+	return strings.Join(list, ", ")
+}
+
+// Select returns the match chosen by the policy. name is the name of the
+// function for SelectExact and ignored otherwise. If no match can be chosen
+// it returns a *NoMatchError, if SelectUnique finds more than one, or
+// SelectExact finds more than one with the name (methods of different
+// types), it returns an *AmbiguityError.
+func (p *Package) Select(policy SelectionPolicy, name string) (*FunctionDetails, error) {
+	switch policy {
+	case SelectExact:
+		if name == "" {
+			return nil, fmt.Errorf("selection policy %s needs the name of the function", policy)
+		}
+		// Methods of different types can have the same name.
+		var found []int
+		for i := range p.Matches {
+			if p.Matches[i].Name == name {
+				found = append(found, i)
+			}
+		}
+		switch {
+		case len(found) == 1:
+			return &p.Matches[found[0]], nil
+		case len(found) > 1:
+			err := &AmbiguityError{}
+			for _, i := range found {
+				err.Matches = append(err.Matches, p.Matches[i])
+			}
+			return nil, err
+		}
+		err := &NoMatchError{Name: name, Candidates: p.Matches}
+		for i := range p.Diagnostics {
+			if p.Diagnostics[i].Name == name {
+				err.Diagnostic = &p.Diagnostics[i]
+				break
+			}
+		}
+		return nil, err
+	case SelectUnique:
+		found, err := p.Check()
+		if err != nil {
+			return nil, err
+		}
+		if found == nil {
+			return nil, &NoMatchError{}
+		}
+		return found, nil
+	case SelectFirstByPriority:
+		var best *FunctionDetails
+		for i := range p.Matches {
+			if best == nil || p.Matches[i].Priority() > best.Priority() {
+				best = &p.Matches[i]
+			}
+		}
+		if best == nil {
+			return nil, &NoMatchError{}
+		}
+		return best, nil
+	}
+	return nil, fmt.Errorf("unknown selection policy %q", policy)
+}
+
+// Priority returns the priority of the signature or interface that matched.
+func (fd *FunctionDetails) Priority() int {
+	switch {
+	case fd.Match != nil:
+		return fd.Match.Priority
+	case fd.Interface != nil:
+		return fd.Interface.Priority
+	}
+	return 0
+}
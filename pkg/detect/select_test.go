@@ -0,0 +1,100 @@
+package detect
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const selectSource = `package fn
+
+import "net/http"
+
+type Request struct{}
+
+func Any(w http.ResponseWriter, r *Request) {}
+
+func Receiver(w http.ResponseWriter, r *http.Request) {}
+
+func Almost(w http.ResponseWriter, r http.Request) {}
+`
+
+func TestSelect(t *testing.T) {
+	d, err := NewDetectorFromString(prioritizedSignatures)
+	if err != nil {
+		t.Fatalf("Failed to read function signatures: %s", err)
+	}
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "fn.go", selectSource, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %s", err)
+	}
+	p := d.ScanFiles(fset, "example.com/fn", ".", []*ast.File{astFile}, nil)
+
+	tests := []struct {
+		policy SelectionPolicy
+		name   string
+		want   string
+		err    string
+	}{
+		{policy: SelectExact, name: "Receiver", want: "Receiver"},
+		{policy: SelectExact, name: "Any", want: "Any"},
+		{policy: SelectExact, name: "Almost", err: "function Almost does not match func(http.ResponseWriter, *_): arg 2 is `http.Request`, expected `*_`"},
+		{policy: SelectExact, name: "Missing", err: "function Missing does not match any signature, the matching functions are: Any (fn.go:7:6), Receiver (fn.go:9:6)"},
+		{policy: SelectExact, err: "needs the name of the function"},
+		{policy: SelectUnique, err: "Found 2 matching signatures"},
+		// Any comes first, but Receiver matches the signature with the
+		// higher priority.
+		{policy: SelectFirstByPriority, name: "Ignored", want: "Receiver"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.policy)+"/"+test.name, func(t *testing.T) {
+			got, err := p.Select(test.policy, test.name)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Wanted error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to select: %s", err)
+			}
+			if got.Name != test.want {
+				t.Errorf("Wanted %s, got %s", test.want, got.Name)
+			}
+		})
+	}
+
+	// Methods with the same name on different receivers.
+	astFile, err = parser.ParseFile(fset, "methods.go", `package fn
+
+import "net/http"
+
+type A struct{}
+
+func (A) Handle(w http.ResponseWriter, r *http.Request) {}
+
+type B struct{}
+
+func (*B) Handle(w http.ResponseWriter, r *http.Request) {}
+`, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %s", err)
+	}
+	p = d.ScanFiles(fset, "example.com/fn", ".", []*ast.File{astFile}, nil)
+	var ambiguous *AmbiguityError
+	if _, err := p.Select(SelectExact, "Handle"); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+		t.Errorf("Wanted an AmbiguityError with both methods, got %v", err)
+	}
+
+	var noMatch *NoMatchError
+	if _, err := (&Package{}).Select(SelectUnique, ""); !errors.As(err, &noMatch) {
+		t.Errorf("Wanted a NoMatchError, got %v", err)
+	}
+	if _, err := ParseSelectionPolicy("best"); err == nil {
+		t.Error("Wanted an error for an unknown policy")
+	}
+}
@@ -0,0 +1,21 @@
+package diagnostics
+
+import (
+	"net/http"
+)
+
+func receive(w http.ResponseWriter, r *http.Request) {
+}
+
+func Receive(w http.ResponseWriter, r http.Request) {
+}
+
+func ReceiveMore(w http.ResponseWriter, r *http.Request, n int) {
+}
+
+func Unrelated(s string) error {
+	return nil
+}
+
+func helper() {
+}
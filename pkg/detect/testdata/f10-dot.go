@@ -0,0 +1,10 @@
+package function
+
+import (
+	"fmt"
+	. "net/http"
+)
+
+func ReceiveDot(writer ResponseWriter, request *Request) {
+	fmt.Println("doing stuff here")
+}
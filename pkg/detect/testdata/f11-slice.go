@@ -0,0 +1,9 @@
+package function
+
+import (
+	"context"
+)
+
+func Process(ctx context.Context, data []byte) ([]byte, error) {
+	return data, nil
+}
@@ -0,0 +1,4 @@
+package function
+
+func Configure(labels map[string]string, digest [4]int, onError func(error)) {
+}
@@ -0,0 +1,12 @@
+package function
+
+import (
+	"context"
+)
+
+func Process(ctx context.Context, data []string) ([]byte, error) {
+	return nil, nil
+}
+
+func Configure(labels map[string]int, digest [8]int, onError func(error)) {
+}
@@ -0,0 +1,9 @@
+package function
+
+import (
+	"context"
+)
+
+func Run(ctx context.Context, args ...string) error {
+	return nil
+}
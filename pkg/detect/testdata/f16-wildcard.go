@@ -0,0 +1,19 @@
+package function
+
+import (
+	"context"
+
+	"example.com/api"
+)
+
+type Request struct {
+	Name string
+}
+
+func Handle(ctx context.Context, req Request) error {
+	return nil
+}
+
+func HandleAPI(ctx context.Context, req *api.Request) (*api.Response, error) {
+	return nil, nil
+}
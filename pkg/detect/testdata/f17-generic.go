@@ -0,0 +1,9 @@
+package function
+
+import (
+	"context"
+)
+
+func Handle[T any](ctx context.Context, in T) (T, error) {
+	return in, nil
+}
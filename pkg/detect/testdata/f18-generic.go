@@ -0,0 +1,20 @@
+package function
+
+import (
+	"context"
+)
+
+// HandleComparable has the wrong constraint.
+func HandleComparable[In comparable](ctx context.Context, in In) (In, error) {
+	return in, nil
+}
+
+// HandleNotGeneric is not generic, so it can't match.
+func HandleNotGeneric(ctx context.Context, in any) (any, error) {
+	return in, nil
+}
+
+// HandleRenamed names the type parameter differently, that's fine.
+func HandleRenamed[X interface{}](ctx context.Context, in X) (X, error) {
+	return in, nil
+}
@@ -0,0 +1,14 @@
+package function
+
+import (
+	"net/http"
+)
+
+func Sum(w http.ResponseWriter, a, b int) {
+}
+
+func Two(a, b http.ResponseWriter, r *http.Request) {
+}
+
+func Named(resp http.ResponseWriter, req *http.Request) {
+}
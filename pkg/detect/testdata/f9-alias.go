@@ -0,0 +1,12 @@
+package function
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Req = http.Request
+
+func ReceiveAlias(writer http.ResponseWriter, request *Req) {
+	fmt.Println("doing stuff here")
+}
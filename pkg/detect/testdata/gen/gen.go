@@ -0,0 +1,26 @@
+package gen
+
+import (
+	"context"
+	"net/http"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+)
+
+type HandlerFunc func(w http.ResponseWriter, r *http.Request)
+
+type Event struct{}
+
+type EventHandler func(ctx context.Context, event Event) (*ce.Event, error)
+
+type Handler interface {
+	ServeHTTP(http.ResponseWriter, *http.Request)
+}
+
+type Transform[T any] func(context.Context, T) ([]T, error)
+
+func Receive(ctx context.Context, event ce.Event) error {
+	return nil
+}
+
+type NotAFunc struct{}
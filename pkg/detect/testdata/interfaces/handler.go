@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"net/http"
+)
+
+// Handler implements http.Handler with a pointer receiver.
+type Handler struct {
+	count int
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.count++
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"net/http"
+)
+
+func (Value) ServeHTTP(http.ResponseWriter, *http.Request) {
+}
+
+type hidden struct{}
+
+func (hidden) ServeHTTP(http.ResponseWriter, *http.Request) {
+}
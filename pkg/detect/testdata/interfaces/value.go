@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	nethttp "net/http"
+)
+
+// Value implements http.Handler with a value receiver.
+type Value struct{}
+
+// Other is not a handler.
+type Other struct{}
+
+func (Other) ServeHTTP(w nethttp.ResponseWriter) {
+}
@@ -0,0 +1,14 @@
+package methods
+
+import (
+	"net/http"
+)
+
+type Handler struct{}
+
+func NewHandler() (Handler, error) {
+	return Handler{}, nil
+}
+
+func (h Handler) Handle(writer http.ResponseWriter, request *http.Request) {
+}
@@ -0,0 +1,15 @@
+package methods
+
+// MakeServer could be used, but NewServer is preferred.
+func MakeServer() *Server {
+	return &Server{port: 8080}
+}
+
+func NewServer() *Server {
+	return &Server{port: 8080}
+}
+
+// NewServerWithPort needs arguments, so it can't be used.
+func NewServerWithPort(port int) *Server {
+	return &Server{port: port}
+}
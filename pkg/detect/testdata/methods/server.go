@@ -0,0 +1,12 @@
+package methods
+
+import (
+	"net/http"
+)
+
+type Server struct {
+	port int
+}
+
+func (s *Server) Receive(writer http.ResponseWriter, request *http.Request) {
+}
@@ -0,0 +1,10 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func ReceiveHTTP(writer http.ResponseWriter, request *http.Request) {
+	fmt.Println("doing stuff here")
+}
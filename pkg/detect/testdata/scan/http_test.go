@@ -0,0 +1,6 @@
+package scan
+
+import "net/http"
+
+func ReceiveTest(writer http.ResponseWriter, request *http.Request) {
+}
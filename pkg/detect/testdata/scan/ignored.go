@@ -0,0 +1,8 @@
+//go:build ignore
+
+package scan
+
+import "net/http"
+
+func ReceiveIgnored(writer http.ResponseWriter, request *http.Request) {
+}
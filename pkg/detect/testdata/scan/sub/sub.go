@@ -0,0 +1,6 @@
+package sub
+
+import "net/http"
+
+func Receive(writer http.ResponseWriter, request *http.Request) {
+}
@@ -0,0 +1,25 @@
+package values
+
+import (
+	"net/http"
+)
+
+func NewStandard() http.HandlerFunc {
+	return handle
+}
+
+func NewLocal() (Handler, error) {
+	return handle, nil
+}
+
+func NewLiteral() func(http.ResponseWriter, *http.Request) {
+	return handle
+}
+
+func NewCount() int {
+	return 1
+}
+
+func NewWithArgs(prefix string) Handler {
+	return handle
+}
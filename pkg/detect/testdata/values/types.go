@@ -0,0 +1,7 @@
+package values
+
+import (
+	"net/http"
+)
+
+type Handler func(http.ResponseWriter, *http.Request)
@@ -0,0 +1,11 @@
+package unsupported
+
+type Pipe func(chan chan int)
+
+var Literal = func(in chan chan int) {}
+
+var Declared Pipe
+
+func NewPipe() Pipe {
+	return nil
+}
@@ -0,0 +1,20 @@
+package values
+
+import (
+	"net/http"
+)
+
+var Literal = func(w http.ResponseWriter, r *http.Request) {}
+
+var Declared func(http.ResponseWriter, *http.Request) = handle
+
+// Standard can only be matched with type checking, since there's no way of
+// knowing what http.HandlerFunc is from the syntax.
+var Standard http.HandlerFunc = handle
+
+var Local Handler = handle
+
+var Count, _ = 1, func(http.ResponseWriter, *http.Request) {}
+
+func handle(w http.ResponseWriter, r *http.Request) {
+}
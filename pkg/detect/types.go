@@ -0,0 +1,272 @@
+package detect
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sync"
+)
+
+// typeChecker loads the packages imported by the scanned source so that
+// argument types can be resolved to what they actually refer to. Imported
+// packages are cached, so a single typeChecker should be reused across
+// checks.
+type typeChecker struct {
+	// mu guards importer, which is not safe for concurrent use.
+	mu       sync.Mutex
+	importer types.ImporterFrom
+}
+
+func newTypeChecker() *typeChecker {
+	// The source importer type checks the imported packages from source, so
+	// it works without any compiled export data being around. It needs a file
+	// set of its own for the imported packages.
+	imp := importer.ForCompiler(token.NewFileSet(), "source", nil)
+	return &typeChecker{importer: imp.(types.ImporterFrom)}
+}
+
+// check type checks the given files as package path and returns whatever
+// type information could be gathered. Errors (for example imports that
+// can't be found) are ignored, the types that couldn't be resolved are
+// simply left invalid and the callers fall back to the syntax for those.
+func (tc *typeChecker) check(fset *token.FileSet, path string, files []*ast.File) *types.Info {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+	}
+	conf := types.Config{
+		Importer: tc.importer,
+		// Keep going on errors so we get as much information as possible.
+		Error: func(error) {},
+	}
+	conf.Check(path, fset, files, info)
+	return info
+}
+
+// resolveSignatures returns a copy of sigs where the arguments are resolved
+// to the types they actually refer to, so that they compare equal to the
+// arguments resolved by typesToFunctionArg. For example an argument declared
+// as "github.com/cloudevents/sdk-go/v2".Event is an alias for
+// "github.com/cloudevents/sdk-go/v2/event".Event. Imports are looked up
+// relative to dir. Arguments that can't be resolved are left as is.
+func (tc *typeChecker) resolveSignatures(sigs []FunctionSignature, dir string) []FunctionSignature {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	ret := make([]FunctionSignature, 0, len(sigs))
+	for _, sig := range sigs {
+		ret = append(ret, tc.resolveSignature(sig, dir))
+	}
+	return ret
+}
+
+// resolveInterfaces is like resolveSignatures, but for the methods of
+// interfaces.
+func (tc *typeChecker) resolveInterfaces(ifaces []InterfaceSignature, dir string) []InterfaceSignature {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	ret := make([]InterfaceSignature, 0, len(ifaces))
+	for _, iface := range ifaces {
+		resolved := InterfaceSignature{}
+		for _, m := range iface.Methods {
+			resolved.Methods = append(resolved.Methods, MethodSignature{Name: m.Name, FunctionSignature: tc.resolveSignature(m.FunctionSignature, dir)})
+		}
+		ret = append(ret, resolved)
+	}
+	return ret
+}
+
+func (tc *typeChecker) resolveSignature(sig FunctionSignature, dir string) FunctionSignature {
+	resolved := FunctionSignature{Receiver: sig.Receiver}
+	for _, tp := range sig.TypeParams {
+		resolved.TypeParams = append(resolved.TypeParams, TypeParam{Name: tp.Name, Constraint: tc.resolveArg(tp.Constraint, dir)})
+	}
+	for _, in := range sig.In {
+		resolved.In = append(resolved.In, tc.resolveArg(in, dir))
+	}
+	for _, out := range sig.Out {
+		resolved.Out = append(resolved.Out, tc.resolveArg(out, dir))
+	}
+	return resolved
+}
+
+func (tc *typeChecker) resolveArg(fa FunctionArg, dir string) FunctionArg {
+	if fa.Key != nil {
+		key := tc.resolveArg(*fa.Key, dir)
+		fa.Key = &key
+	}
+	if fa.Elem != nil {
+		elem := tc.resolveArg(*fa.Elem, dir)
+		fa.Elem = &elem
+	}
+	if fa.Func != nil {
+		sig := tc.resolveSignature(*fa.Func, dir)
+		fa.Func = &sig
+	}
+	if fa.ImportPath == "" {
+		return fa
+	}
+	pkg, err := tc.importer.ImportFrom(fa.ImportPath, dir, 0)
+	if err != nil {
+		return fa
+	}
+	tn, ok := pkg.Scope().Lookup(fa.Name).(*types.TypeName)
+	if !ok {
+		return fa
+	}
+	resolved, ok := named(tn.Type())
+	if !ok {
+		return fa
+	}
+	fa.ImportPath = resolved.ImportPath
+	fa.Name = resolved.Name
+	return fa
+}
+
+// argResolver turns type expressions into FunctionArgs. If type information
+// is available it's used, otherwise (or if the type couldn't be resolved) the
+// expression is mapped based on the syntax alone.
+type argResolver struct {
+	// imports maps the local import names to the full import paths.
+	imports map[string]string
+	// info is nil if the source was not type checked.
+	info *types.Info
+	// sigs are the signatures resolved with the same type information, in
+	// the same order as the signatures of the Detector. It's nil if the
+	// source was not type checked.
+	sigs []FunctionSignature
+	// funcTypes are the func types declared in the package, see
+	// funcSignature.
+	funcTypes map[string]FunctionSignature
+}
+
+func (r *argResolver) resolve(e ast.Expr) FunctionArg {
+	// The type of the ... itself is not recorded, only that of the elements.
+	if ellipsis, ok := e.(*ast.Ellipsis); ok {
+		fa := r.resolve(ellipsis.Elt)
+		fa.Variadic = true
+		return fa
+	}
+	if r.info != nil {
+		if fa, ok := typesToFunctionArg(r.info.TypeOf(e)); ok {
+			return fa
+		}
+	}
+	return typeToFunctionArg(r.imports, e)
+}
+
+// typesToFunctionArg is the go/types counterpart of typeToFunctionArg. It
+// returns false if the type (or any part of it) is not something that can be
+// expressed as a FunctionArg or could not be resolved.
+func typesToFunctionArg(t types.Type) (FunctionArg, bool) {
+	if t == nil {
+		return FunctionArg{}, false
+	}
+	switch t := types.Unalias(t).(type) {
+	case *types.Chan:
+		fa, ok := typesToFunctionArg(t.Elem())
+		// Channels of channels can not be expressed.
+		if fa.Channel != "" {
+			return FunctionArg{}, false
+		}
+		switch t.Dir() {
+		case types.RecvOnly:
+			fa.Channel = Receive
+		case types.SendOnly:
+			fa.Channel = Send
+		case types.SendRecv:
+			fa.Channel = Both
+		}
+		return fa, ok
+	case *types.Pointer:
+		fa, ok := typesToFunctionArg(t.Elem())
+		// Pointers to pointers or channels can not be expressed.
+		if fa.Pointer || fa.Channel != "" {
+			return FunctionArg{}, false
+		}
+		fa.Pointer = true
+		return fa, ok
+	case *types.Slice:
+		elem, ok := typesToFunctionArg(t.Elem())
+		return FunctionArg{Slice: true, Elem: &elem}, ok
+	case *types.Array:
+		elem, ok := typesToFunctionArg(t.Elem())
+		return FunctionArg{ArrayLen: int(t.Len()), Elem: &elem}, ok && t.Len() > 0
+	case *types.Map:
+		key, keyOK := typesToFunctionArg(t.Key())
+		elem, elemOK := typesToFunctionArg(t.Elem())
+		return FunctionArg{Key: &key, Elem: &elem}, keyOK && elemOK
+	case *types.Signature:
+		sig, ok := typesToSignature(t)
+		return FunctionArg{Func: &sig}, ok
+	case *types.TypeParam:
+		return FunctionArg{Name: t.Obj().Name()}, true
+	case *types.Interface:
+		// The empty interface is the same as any, which is mostly useful
+		// for type parameter constraints.
+		if t.Empty() {
+			return FunctionArg{Name: "any"}, true
+		}
+		return FunctionArg{}, false
+	}
+	return named(t)
+}
+
+// typesToSignature maps the parameters and results of a function type to a
+// FunctionSignature.
+func typesToSignature(t *types.Signature) (FunctionSignature, bool) {
+	fs := FunctionSignature{}
+	for i := 0; i < t.Params().Len(); i++ {
+		pt := t.Params().At(i).Type()
+		// The type of the last parameter of a variadic function is a slice.
+		variadic := t.Variadic() && i == t.Params().Len()-1
+		if variadic {
+			pt = pt.(*types.Slice).Elem()
+		}
+		fa, ok := typesToFunctionArg(pt)
+		if !ok {
+			return fs, false
+		}
+		fa.Variadic = variadic
+		fa.ParamName = t.Params().At(i).Name()
+		fs.In = append(fs.In, fa)
+	}
+	for i := 0; i < t.Results().Len(); i++ {
+		fa, ok := typesToFunctionArg(t.Results().At(i).Type())
+		if !ok {
+			return fs, false
+		}
+		fa.ParamName = t.Results().At(i).Name()
+		fs.Out = append(fs.Out, fa)
+	}
+	return fs, true
+}
+
+func named(t types.Type) (FunctionArg, bool) {
+	switch t := types.Unalias(t).(type) {
+	case *types.Named:
+		obj := t.Obj()
+		// Predeclared types like error do not have a package.
+		if obj.Pkg() == nil {
+			return FunctionArg{Name: obj.Name()}, true
+		}
+		return FunctionArg{ImportPath: obj.Pkg().Path(), Name: obj.Name()}, true
+	case *types.Basic:
+		if t.Kind() == types.Invalid {
+			return FunctionArg{}, false
+		}
+		return FunctionArg{Name: t.Name()}, true
+	}
+	return FunctionArg{}, false
+}
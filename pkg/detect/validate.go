@@ -0,0 +1,295 @@
+package detect
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema of the config the signatures are read
+// from, see FunctionSignatures. YAML and TOML configs are validated as if
+// they were converted to JSON.
+func Schema() []byte {
+	return bytes.Clone(schemaJSON)
+}
+
+var compileSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("schema.json", bytes.NewReader(schemaJSON)); err != nil {
+		return nil, err
+	}
+	return c.Compile("schema.json")
+})
+
+// ValidationError is returned if a config doesn't conform to the Schema.
+type ValidationError struct {
+	// Format is the format the config was parsed as.
+	Format Format
+	// Source is where the config was loaded from, if known.
+	Source Source
+	// Problems are sorted by line.
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		problems = append(problems, p.String())
+	}
+	return e.Source.wrap(fmt.Errorf("invalid %s config: %s", e.Format, strings.Join(problems, "; "))).Error()
+}
+
+// Problem is a single violation of the Schema.
+type Problem struct {
+	// Line is the line of the config the problem is on, starting at 1. It's
+	// 0 if the line is unknown.
+	Line int
+	// Path is the JSON pointer to the offending value, for example
+	// /functionSignatures/0/in/1/channel.
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	msg := p.Message
+	if p.Path != "" {
+		msg = p.Path + ": " + msg
+	}
+	if p.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", p.Line, msg)
+	}
+	return msg
+}
+
+// Validate checks the config against the Schema and returns the format it
+// was parsed as. Configs starting with { are JSON, the ones starting with a
+// [table] or key = value are TOML and everything else is YAML. If the config
+// doesn't conform to the Schema, which means it has unknown fields, invalid
+// values or no signatures at all, the error is a *ValidationError.
+func Validate(config string) (Format, error) {
+	format := detectFormat(config)
+	return format, validate(config, format)
+}
+
+var tomlKey = regexp.MustCompile(`^[A-Za-z0-9_.'"-]+\s*=`)
+
+// detectFormat returns the format the config is written in, see Validate.
+func detectFormat(config string) Format {
+	if strings.HasPrefix(strings.TrimSpace(config), "{") {
+		return JSON
+	}
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") || tomlKey.MatchString(line) {
+			return TOML
+		}
+		return YAML
+	}
+	return YAML
+}
+
+// validate checks the config, written in format, against the Schema.
+func validate(config string, format Format) error {
+	schema, err := compileSchema()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	// lines maps JSON pointers to the lines they are on.
+	lines := map[string]int{"": 1}
+	switch format {
+	case JSON, YAML:
+		if format == JSON && !json.Valid([]byte(config)) {
+			var v interface{}
+			return syntaxError(config, format, json.Unmarshal([]byte(config), &v))
+		}
+		var node yaml3.Node
+		if err := yaml3.Unmarshal([]byte(config), &node); err != nil {
+			return fmt.Errorf("invalid %s config: %w", format, err)
+		}
+		yamlLines(&node, "", lines)
+		if data, err = yaml.YAMLToJSON([]byte(config)); err != nil {
+			return fmt.Errorf("invalid %s config: %w", format, err)
+		}
+	case TOML:
+		var v map[string]interface{}
+		if _, err := toml.Decode(config, &v); err != nil {
+			return fmt.Errorf("invalid %s config: %w", format, err)
+		}
+		tomlLines(config, lines)
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	// The numbers are kept as json.Number, which is what the validator
+	// expects.
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	err = schema.Validate(doc)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+	verr := &ValidationError{Format: format}
+	verr.add(ve, lines)
+	sort.SliceStable(verr.Problems, func(i, j int) bool {
+		return verr.Problems[i].Line < verr.Problems[j].Line
+	})
+	return verr
+}
+
+// syntaxError adds the line to the error of encoding/json, which only knows
+// the offset.
+func syntaxError(config string, format Format, err error) error {
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		line := strings.Count(config[:se.Offset], "\n") + 1
+		return fmt.Errorf("invalid %s config: line %d: %w", format, line, err)
+	}
+	return fmt.Errorf("invalid %s config: %w", format, err)
+}
+
+var quoted = regexp.MustCompile(`'([^']*)'`)
+
+// add turns the leaves of the validation errors into Problems.
+func (e *ValidationError) add(ve *jsonschema.ValidationError, lines map[string]int) {
+	switch {
+	case ve.KeywordLocation == "/anyOf":
+		// The anyOf at the top requires signatures or interfaces, the
+		// details of the branches are just confusing. So are the ones of
+		// the types below.
+		e.Problems = append(e.Problems, Problem{Line: lineOf(lines, ""), Message: "at least one function signature or interface is required"})
+	case strings.HasSuffix(ve.AbsoluteKeywordLocation, "/$defs/functionArg/anyOf"):
+		e.Problems = append(e.Problems, Problem{Line: lineOf(lines, ve.InstanceLocation), Path: ve.InstanceLocation, Message: "the type needs a name, or one of slice, arrayLen, key or func"})
+	case len(ve.Causes) > 0:
+		for _, c := range ve.Causes {
+			e.add(c, lines)
+		}
+	case strings.HasSuffix(ve.KeywordLocation, "/additionalProperties"):
+		// Point at the unknown fields rather than the object they're in.
+		for _, m := range quoted.FindAllStringSubmatch(ve.Message, -1) {
+			path := ve.InstanceLocation + "/" + escapePointer(m[1])
+			e.Problems = append(e.Problems, Problem{Line: lineOf(lines, path), Path: path, Message: fmt.Sprintf("unknown field %q", m[1])})
+		}
+	default:
+		e.Problems = append(e.Problems, Problem{Line: lineOf(lines, ve.InstanceLocation), Path: ve.InstanceLocation, Message: ve.Message})
+	}
+}
+
+// lineOf returns the line of the value at the JSON pointer path, or of the
+// closest of its parents that has a known line.
+func lineOf(lines map[string]int, path string) int {
+	for {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			return 0
+		}
+		path = path[:i]
+	}
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// yamlLines records the lines of the values below node, which is at the
+// JSON pointer path. For fields that's the line of the key.
+func yamlLines(node *yaml3.Node, path string, lines map[string]int) {
+	if _, ok := lines[path]; !ok && node.Line > 0 {
+		lines[path] = node.Line
+	}
+	switch node.Kind {
+	case yaml3.DocumentNode:
+		for _, n := range node.Content {
+			yamlLines(n, path, lines)
+		}
+	case yaml3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := path + "/" + escapePointer(node.Content[i].Value)
+			lines[key] = node.Content[i].Line
+			yamlLines(node.Content[i+1], key, lines)
+		}
+	case yaml3.SequenceNode:
+		for i, n := range node.Content {
+			yamlLines(n, path+"/"+strconv.Itoa(i), lines)
+		}
+	}
+}
+
+var tomlTable = regexp.MustCompile(`^(\[\[?)\s*([^\[\]]+?)\s*\]`)
+
+// tomlLines records the lines of the tables and keys of a TOML config. The
+// values of inline tables and arrays are not recorded, they're on the line
+// of their key anyway (unless they span multiple lines).
+func tomlLines(config string, lines map[string]int) {
+	// tables is the number of tables of each array of tables so far.
+	tables := map[string]int{}
+	table := ""
+	for i, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if m := tomlTable.FindStringSubmatch(line); m != nil {
+			keys := strings.Split(m[2], ".")
+			if m[1] == "[[" {
+				array := tomlPointer(tables, "", keys[:len(keys)-1]) + "/" + escapePointer(tomlKeyName(keys[len(keys)-1]))
+				if _, ok := lines[array]; !ok {
+					lines[array] = i + 1
+				}
+				table = array + "/" + strconv.Itoa(tables[array])
+				tables[array]++
+			} else {
+				table = tomlPointer(tables, "", keys)
+			}
+			lines[table] = i + 1
+			continue
+		}
+		if tomlKey.MatchString(line) {
+			key := strings.TrimSpace(line[:strings.Index(line, "=")])
+			lines[tomlPointer(tables, table, strings.Split(key, "."))] = i + 1
+		}
+	}
+}
+
+// tomlPointer returns the JSON pointer of the dotted keys relative to the
+// table at path. Arrays of tables refer to their last table, like they do
+// in the TOML table headers.
+func tomlPointer(tables map[string]int, path string, keys []string) string {
+	for _, key := range keys {
+		path += "/" + escapePointer(tomlKeyName(key))
+		if n, ok := tables[path]; ok {
+			path += "/" + strconv.Itoa(n-1)
+		}
+	}
+	return path
+}
+
+func tomlKeyName(key string) string {
+	return strings.Trim(strings.TrimSpace(key), `"'`)
+}
@@ -0,0 +1,165 @@
+package detect
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for file, want := range map[string]Format{
+		signatureFileJSON: JSON,
+		signatureFileYAML: YAML,
+		signatureFileTOML: TOML,
+	} {
+		config, err := readFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s", file, err)
+		}
+		format, err := Validate(config)
+		if err != nil {
+			t.Errorf("Failed to validate %s: %s", file, err)
+		}
+		if format != want {
+			t.Errorf("Wanted %s to be %s, got %s", file, want, format)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		format   Format
+		problems []string
+	}{{
+		name: "yaml unknown field",
+		config: `functionSignatures:
+  - in:
+      - importpath: net/http
+        name: ResponseWriter
+`,
+		format:   YAML,
+		problems: []string{`line 3: /functionSignatures/0/in/0/importpath: unknown field "importpath"`},
+	}, {
+		name: "yaml channel",
+		config: `# Channels.
+functionSignatures:
+  - in:
+      - name: int
+        channel: RECV
+`,
+		format:   YAML,
+		problems: []string{`line 5: /functionSignatures/0/in/0/channel: value must be one of "SEND", "RECEIVE", "BOTH"`},
+	}, {
+		name:     "yaml empty",
+		config:   "functionSignatures:\n",
+		format:   YAML,
+		problems: []string{"line 1: at least one function signature or interface is required"},
+	}, {
+		name: "json",
+		config: `{
+  "functionSignatures": [
+    {"in": [{"name": "int", "slice": "yes"}]}
+  ],
+  "import": {}
+}`,
+		format: JSON,
+		problems: []string{
+			"line 3: /functionSignatures/0/in/0/slice: expected boolean, but got string",
+			`line 5: /import: unknown field "import"`,
+		},
+	}, {
+		name: "toml",
+		config: `[[functionSignatures]]
+[[functionSignatures.in]]
+name = "int"
+
+[[functionSignatures]]
+id = "second"
+[[functionSignatures.out]]
+name = "error"
+channel = "BOTH"
+[[functionSignatures.out]]
+name = "error"
+pointers = true
+`,
+		format:   TOML,
+		problems: []string{`line 12: /functionSignatures/1/out/1/pointers: unknown field "pointers"`},
+	}, {
+		name: "yaml type without a name",
+		config: `functionSignatures:
+  - in:
+      - pointer: false
+      - slice: true
+        elem:
+          name: byte
+      - slice: false
+        elem: {}
+`,
+		format: YAML,
+		problems: []string{
+			"line 3: /functionSignatures/0/in/0: the type needs a name, or one of slice, arrayLen, key or func",
+			"line 7: /functionSignatures/0/in/2: the type needs a name, or one of slice, arrayLen, key or func",
+			"line 8: /functionSignatures/0/in/2/elem: the type needs a name, or one of slice, arrayLen, key or func",
+		},
+	}, {
+		name:     "toml interfaces",
+		config:   "[[interfaces]]\nid = \"handler\"\n",
+		format:   TOML,
+		problems: []string{`line 1: /interfaces/0: missing properties: 'methods'`},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := Validate(test.config)
+			if format != test.format {
+				t.Errorf("Wanted format %s, got %s", test.format, format)
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Wanted a ValidationError, got %v", err)
+			}
+			var got []string
+			for _, p := range verr.Problems {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.problems, "\n") {
+				t.Errorf("Wanted problems:\n%s\ngot:\n%s", strings.Join(test.problems, "\n"), strings.Join(got, "\n"))
+			}
+			if _, err := NewDetectorFromString(test.config); err == nil {
+				t.Error("Wanted NewDetectorFromString to fail")
+			}
+		})
+	}
+}
+
+func TestValidateSyntax(t *testing.T) {
+	tests := []struct {
+		config string
+		format Format
+		err    string
+	}{
+		// This used to be reported as a TOML error.
+		{config: "functionSignatures:\n  - in: [\n", format: YAML, err: "invalid yaml config: yaml: line 2"},
+		{config: "{\n  \"functionSignatures\": [],\n}", format: JSON, err: "invalid json config: line 3"},
+		{config: "[[functionSignatures]\n", format: TOML, err: "invalid toml config"},
+	}
+	for _, test := range tests {
+		format, err := Validate(test.config)
+		if format != test.format {
+			t.Errorf("Wanted format %s, got %s", test.format, format)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Wanted error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("Failed to parse the schema: %s", err)
+	}
+	if _, err := compileSchema(); err != nil {
+		t.Fatalf("Failed to compile the schema: %s", err)
+	}
+}
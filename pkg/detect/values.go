@@ -0,0 +1,135 @@
+package detect
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// funcTypes adds the func types declared in astFile to declared, keyed by
+// the name of the type. For example:
+// type Handler func(http.ResponseWriter, *http.Request)
+func funcTypes(resolver *argResolver, astFile *ast.File, declared map[string]FunctionSignature) {
+	for _, decl := range astFile.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ft, ok := ts.Type.(*ast.FuncType); ok && ts.TypeParams == nil {
+				declared[ts.Name.Name] = resolver.signature(ft)
+			}
+		}
+	}
+}
+
+// funcSignature returns the signature of the function that e, which is
+// either a type or a value, is or evaluates to. Without type information
+// only func types, func literals and the func types declared in the package
+// itself are understood, so for example http.HandlerFunc needs
+// WithTypeChecking.
+func (r *argResolver) funcSignature(e ast.Expr) (FunctionSignature, bool) {
+	if r.info != nil {
+		if t := r.info.TypeOf(e); t != nil {
+			if sig, ok := t.Underlying().(*types.Signature); ok && sig.TypeParams() == nil {
+				if fs, ok := typesToSignature(sig); ok {
+					return fs, true
+				}
+			}
+		}
+	}
+	switch e := e.(type) {
+	case *ast.FuncType:
+		return r.signature(e), true
+	case *ast.FuncLit:
+		return r.signature(e.Type), true
+	case *ast.Ident:
+		fs, ok := r.funcTypes[e.Name]
+		return fs, ok
+	}
+	return FunctionSignature{}, false
+}
+
+// checkVariables returns the details of the package level variables declared
+// by spec that hold a function matching one of the signatures. For example:
+// var Handler = func(w http.ResponseWriter, r *http.Request) {}
+// var Handler http.HandlerFunc = handle
+// Like functions, variables holding a function that uses unsupported types
+// are not matched, the types are returned instead.
+func (d *Detector) checkVariables(fset *token.FileSet, resolver *argResolver, spec *ast.ValueSpec) ([]FunctionDetails, []error) {
+	var retval []FunctionDetails
+	var errs []error
+	for i, name := range spec.Names {
+		if name.Name == "_" || !d.usable(name) {
+			continue
+		}
+		// The declared type wins over the type of the value.
+		e := spec.Type
+		if e == nil {
+			if len(spec.Values) != len(spec.Names) {
+				continue
+			}
+			e = spec.Values[i]
+		}
+		fs, ok := resolver.funcSignature(e)
+		if !ok {
+			continue
+		}
+		if unsupported := unsupportedSignature(fset, resolver, name.Name, e, fs); len(unsupported) > 0 {
+			for _, err := range unsupported {
+				d.logger.Warn("Skipping unsupported type", "error", err)
+			}
+			errs = append(errs, unsupported...)
+			continue
+		}
+		if details := d.checkSignature(resolver, fs, false); details != nil {
+			details.Kind = KindVariable
+			details.Name = name.Name
+			details.Position = fset.Position(name.Pos())
+			retval = append(retval, *details)
+		}
+	}
+	return retval, errs
+}
+
+// checkFactory returns the details of f if it's a factory, that is a
+// function without a receiver or parameters that returns a function matching
+// one of the signatures, optionally followed by an error. For example:
+// func NewHandler() http.HandlerFunc
+// func NewHandler() (func(http.ResponseWriter, *http.Request), error)
+// Params and Results are the ones of the returned function. If the returned
+// function uses unsupported types, they are returned instead.
+func (d *Detector) checkFactory(fset *token.FileSet, resolver *argResolver, f *ast.FuncDecl) (*FunctionDetails, []error) {
+	if f.Recv != nil || f.Type.TypeParams != nil || f.Type.Results == nil {
+		return nil, nil
+	}
+	if f.Type.Params != nil && len(f.Type.Params.List) > 0 {
+		return nil, nil
+	}
+	var results []ast.Expr
+	for _, r := range f.Type.Results.List {
+		results = append(results, expandFieldTypes(r)...)
+	}
+	if len(results) == 2 {
+		if id, ok := results[1].(*ast.Ident); !ok || id.Name != "error" {
+			return nil, nil
+		}
+	} else if len(results) != 1 {
+		return nil, nil
+	}
+	fs, ok := resolver.funcSignature(results[0])
+	if !ok {
+		return nil, nil
+	}
+	if unsupported := unsupportedSignature(fset, resolver, f.Name.Name, results[0], fs); len(unsupported) > 0 {
+		return nil, unsupported
+	}
+	details := d.checkSignature(resolver, fs, false)
+	if details == nil {
+		return nil, nil
+	}
+	details.Kind = KindFactory
+	details.FactoryError = len(results) == 2
+	return details, nil
+}
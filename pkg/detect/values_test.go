@@ -0,0 +1,124 @@
+package detect
+
+import (
+	"errors"
+	"testing"
+)
+
+const valuesDir = "./testdata/values"
+
+func TestValues(t *testing.T) {
+	type match struct {
+		kind         Kind
+		name         string
+		factoryError bool
+	}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want []match
+	}{{
+		name: "syntax",
+		want: []match{
+			{kind: KindVariable, name: "Literal"},
+			{kind: KindVariable, name: "Declared"},
+			{kind: KindVariable, name: "Local"},
+			{kind: KindFunction, name: "handle"},
+		},
+	}, {
+		name: "typechecked",
+		opts: []Option{WithTypeChecking()},
+		want: []match{
+			{kind: KindVariable, name: "Literal"},
+			{kind: KindVariable, name: "Declared"},
+			{kind: KindVariable, name: "Standard"},
+			{kind: KindVariable, name: "Local"},
+			{kind: KindFunction, name: "handle"},
+		},
+	}, {
+		name: "factories",
+		opts: []Option{WithFactories()},
+		want: []match{
+			{kind: KindFactory, name: "NewLocal", factoryError: true},
+			{kind: KindFactory, name: "NewLiteral"},
+			{kind: KindVariable, name: "Literal"},
+			{kind: KindVariable, name: "Declared"},
+			{kind: KindVariable, name: "Local"},
+			{kind: KindFunction, name: "handle"},
+		},
+	}, {
+		name: "typechecked factories",
+		opts: []Option{WithTypeChecking(), WithFactories()},
+		want: []match{
+			{kind: KindFactory, name: "NewStandard"},
+			{kind: KindFactory, name: "NewLocal", factoryError: true},
+			{kind: KindFactory, name: "NewLiteral"},
+			{kind: KindVariable, name: "Literal"},
+			{kind: KindVariable, name: "Declared"},
+			{kind: KindVariable, name: "Standard"},
+			{kind: KindVariable, name: "Local"},
+			{kind: KindFunction, name: "handle"},
+		},
+	}, {
+		name: "exported only",
+		opts: []Option{WithFactories(), WithExportedOnly()},
+		want: []match{
+			{kind: KindFactory, name: "NewLocal", factoryError: true},
+			{kind: KindFactory, name: "NewLiteral"},
+			{kind: KindVariable, name: "Literal"},
+			{kind: KindVariable, name: "Declared"},
+			{kind: KindVariable, name: "Local"},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDetector([]FunctionSignature{httpSignature("")}, tc.opts...)
+			p, err := d.ScanPackage(valuesDir)
+			if err != nil {
+				t.Fatalf("Failed to scan package: %s", err)
+			}
+			if len(p.Matches) != len(tc.want) {
+				t.Fatalf("Wanted %v, got %+v", tc.want, p.Matches)
+			}
+			for i, want := range tc.want {
+				got := p.Matches[i]
+				if got.Kind != want.kind || got.Name != want.name || got.FactoryError != want.factoryError {
+					t.Errorf("Error at %d, wanted %+v, got %+v", i, want, got)
+				}
+				if got.Signature != "func(http.ResponseWriter, *http.Request)" {
+					t.Errorf("Error at %d, unexpected signature %q", i, got.Signature)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsupportedValues(t *testing.T) {
+	// The wildcard would match the chan chan int of all of them, which are
+	// reported instead.
+	d := NewDetector([]FunctionSignature{{In: []FunctionArg{{Name: Wildcard}}}}, WithFactories())
+	p, err := d.ScanPackage(valuesDir + "/unsupported")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	if len(p.Matches) != 0 {
+		t.Errorf("Wanted no matches, got %+v", p.Matches)
+	}
+	want := []UnsupportedTypeError{
+		{Name: "Literal", Type: "chan chan int"},
+		{Name: "Declared", Type: "Pipe"},
+		{Name: "NewPipe", Type: "Pipe"},
+	}
+	wantLines := []int{5, 7, 9}
+	if len(p.Errors) != len(want) {
+		t.Fatalf("Wanted %d errors, got %v", len(want), p.Errors)
+	}
+	for i := range want {
+		var got *UnsupportedTypeError
+		if !errors.As(p.Errors[i], &got) {
+			t.Fatalf("Error at %d, wanted an UnsupportedTypeError, got %v", i, p.Errors[i])
+		}
+		if got.Name != want[i].Name || got.Type != want[i].Type || got.Position.Line != wantLines[i] {
+			t.Errorf("Error at %d, wanted %s at line %d, got %v", i, want[i].Name, wantLines[i], got)
+		}
+	}
+}
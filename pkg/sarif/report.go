@@ -0,0 +1,234 @@
+package sarif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+// Rule IDs of the results. The ones for matches and near misses get the ID
+// of the signature appended, for example "match/http", so that dashboards
+// can tell them apart.
+const (
+	RuleMatch       = "match"
+	RuleNearMiss    = "near-miss"
+	RuleAmbiguous   = "ambiguous"
+	RuleParseError  = "parse-error"
+	RuleUnsupported = "unsupported-type"
+)
+
+const (
+	toolName = "gofunctypechecker"
+	toolURI  = "https://github.com/vaikas/gofunctypechecker"
+)
+
+// Report collects the results of the detector and turns them into a Log.
+type Report struct {
+	baseDir       string
+	rules         []Rule
+	ruleIndex     map[string]int
+	results       []Result
+	notifications []Notification
+}
+
+// NewReport returns an empty Report. The files below baseDir are written
+// relative to it, with SrcRoot as their uriBaseId, which is what code
+// scanning dashboards expect. An empty baseDir leaves the paths as they are.
+func NewReport(baseDir string) *Report {
+	if baseDir != "" {
+		if abs, err := filepath.Abs(baseDir); err == nil {
+			baseDir = abs
+		}
+	}
+	return &Report{baseDir: baseDir, ruleIndex: make(map[string]int)}
+}
+
+// AddPackage adds the matches, the near misses and the errors of the
+// package.
+func (r *Report) AddPackage(p *detect.Package) {
+	r.AddMatches(p.Matches)
+	r.AddDiagnostics(p.Diagnostics)
+	for _, err := range p.Errors {
+		r.AddError(err)
+	}
+}
+
+// AddMatches adds a note for each of the matches.
+func (r *Report) AddMatches(matches []detect.FunctionDetails) {
+	for i := range matches {
+		m := &matches[i]
+		id, description := RuleMatch, ""
+		switch {
+		case m.Match != nil:
+			id, description = ruleID(RuleMatch, m.Match.ID), m.Match.Description
+		case m.Interface != nil:
+			id, description = ruleID(RuleMatch, m.Interface.ID), m.Interface.Description
+		}
+		r.add(Result{
+			RuleID:    id,
+			Level:     LevelNote,
+			Message:   Message{Text: fmt.Sprintf("%s %s matches %s", m.Kind, m.Name, m.Signature)},
+			Locations: []Location{r.location(m.Position, len(m.Name), "")},
+		}, "Declaration matching "+m.Signature, description)
+	}
+}
+
+// AddDiagnostics adds a warning for each of the near misses.
+func (r *Report) AddDiagnostics(diags []detect.Diagnostic) {
+	for i := range diags {
+		diag := &diags[i]
+		id, description := RuleNearMiss, ""
+		if diag.Closest != nil {
+			id, description = ruleID(RuleNearMiss, diag.Closest.ID), diag.Closest.Description
+		}
+		r.add(Result{
+			RuleID:    id,
+			Level:     LevelWarning,
+			Message:   Message{Text: fmt.Sprintf("%s does not match %s: %s", diag.Name, diag.Signature, strings.Join(diag.Reasons, ", "))},
+			Locations: []Location{r.location(diag.Position, len(diag.Name), "")},
+		}, "Function almost matching "+diag.Signature, description)
+	}
+}
+
+// AddError adds an error returned by the detector. An *detect.AmbiguityError
+// becomes a result at the first match, with the others as related
+// locations, a *detect.UnsupportedTypeError becomes a warning and the syntax
+// errors of the parser become a result each. Any other error is added as a
+// notification that makes the run unsuccessful.
+func (r *Report) AddError(err error) {
+	var ambiguous *detect.AmbiguityError
+	var syntax scanner.ErrorList
+	var single *scanner.Error
+	var unsupported *detect.UnsupportedTypeError
+	switch {
+	case errors.As(err, &ambiguous) && len(ambiguous.Matches) > 0:
+		result := Result{
+			RuleID:  RuleAmbiguous,
+			Level:   LevelError,
+			Message: Message{Text: ambiguous.Error()},
+		}
+		for i, m := range ambiguous.Matches {
+			loc := r.location(m.Position, len(m.Name), fmt.Sprintf("%s %s", m.Kind, m.Name))
+			if i == 0 {
+				result.Locations = append(result.Locations, loc)
+			} else {
+				result.RelatedLocations = append(result.RelatedLocations, loc)
+			}
+		}
+		r.add(result, "More than one declaration matches where one is expected", "")
+	case errors.As(err, &unsupported):
+		r.add(Result{
+			RuleID:    RuleUnsupported,
+			Level:     LevelWarning,
+			Message:   Message{Text: fmt.Sprintf("%s uses unsupported type `%s` and can not be checked", unsupported.Name, unsupported.Type)},
+			Locations: []Location{r.location(unsupported.Position, len(unsupported.Type), "")},
+		}, "Function using a type that can not be checked", "")
+	case errors.As(err, &syntax):
+		for _, e := range syntax {
+			r.addSyntaxError(e)
+		}
+	case errors.As(err, &single):
+		r.addSyntaxError(single)
+	default:
+		r.notifications = append(r.notifications, Notification{Level: LevelError, Message: Message{Text: err.Error()}})
+	}
+}
+
+func (r *Report) addSyntaxError(e *scanner.Error) {
+	r.add(Result{
+		RuleID:    RuleParseError,
+		Level:     LevelError,
+		Message:   Message{Text: e.Msg},
+		Locations: []Location{r.location(e.Pos, 0, "")},
+	}, "The source could not be parsed", "")
+}
+
+// Log returns the SARIF log of everything that was added so far.
+func (r *Report) Log() *Log {
+	run := Run{
+		Tool: Tool{Driver: Driver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          r.rules,
+		}},
+		Results: r.results,
+	}
+	if run.Results == nil {
+		run.Results = []Result{}
+	}
+	if len(r.notifications) > 0 {
+		run.Invocations = []Invocation{{ExecutionSuccessful: false, ToolExecutionNotifications: r.notifications}}
+	}
+	return &Log{Version: Version, Schema: Schema, Runs: []Run{run}}
+}
+
+// Write writes the log as indented JSON to w.
+func (r *Report) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(r.Log(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// add adds the result, along with its rule if it's the first result for
+// it.
+func (r *Report) add(result Result, short, full string) {
+	index, ok := r.ruleIndex[result.RuleID]
+	if !ok {
+		rule := Rule{ID: result.RuleID, ShortDescription: &Message{Text: short}}
+		if full != "" {
+			rule.FullDescription = &Message{Text: full}
+		}
+		index = len(r.rules)
+		r.rules = append(r.rules, rule)
+		r.ruleIndex[result.RuleID] = index
+	}
+	result.RuleIndex = index
+	r.results = append(r.results, result)
+}
+
+// location returns the location of position, spanning length columns if
+// it's not 0.
+func (r *Report) location(position token.Position, length int, message string) Location {
+	loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: r.artifact(position.Filename)}}
+	if position.Line > 0 {
+		region := &Region{StartLine: position.Line, StartColumn: position.Column}
+		if length > 0 && position.Column > 0 {
+			region.EndLine = position.Line
+			region.EndColumn = position.Column + length
+		}
+		loc.PhysicalLocation.Region = region
+	}
+	if message != "" {
+		loc.Message = &Message{Text: message}
+	}
+	return loc
+}
+
+func (r *Report) artifact(filename string) ArtifactLocation {
+	if r.baseDir != "" {
+		if abs, err := filepath.Abs(filename); err == nil {
+			if rel, err := filepath.Rel(r.baseDir, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return ArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: SrcRoot}
+			}
+		}
+	}
+	return ArtifactLocation{URI: filepath.ToSlash(filename)}
+}
+
+// ruleID returns the rule for a signature with the id, if it has one.
+func ruleID(rule, id string) string {
+	if id == "" {
+		return rule
+	}
+	return rule + "/" + id
+}
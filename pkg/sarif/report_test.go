@@ -0,0 +1,147 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vaikas/gofunctypechecker/pkg/detect"
+)
+
+var httpSignature = detect.FunctionSignature{
+	SignatureInfo: detect.SignatureInfo{ID: "http", Description: "HTTP handlers"},
+	In: []detect.FunctionArg{
+		{ImportPath: "net/http", Name: "ResponseWriter"},
+		{ImportPath: "net/http", Name: "Request", Pointer: true},
+	},
+}
+
+const twoHandlers = `package function
+
+import "net/http"
+
+func One(w http.ResponseWriter, r *http.Request) {}
+
+func Two(w http.ResponseWriter, r *http.Request) {}
+`
+
+func TestMatchesAndAmbiguity(t *testing.T) {
+	d := detect.NewDetector([]detect.FunctionSignature{httpSignature})
+	matches, err := d.AllFromFile(&detect.Function{File: "handlers/two.go", Source: twoHandlers})
+	if err != nil {
+		t.Fatalf("Failed to check file: %s", err)
+	}
+	_, err = d.CheckFile(&detect.Function{File: "handlers/two.go", Source: twoHandlers})
+	if err == nil {
+		t.Fatal("Wanted an ambiguity error")
+	}
+
+	r := NewReport("")
+	r.AddMatches(matches)
+	r.AddError(err)
+	log := r.Log()
+
+	if log.Version != Version || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected log %+v", log)
+	}
+	run := log.Runs[0]
+	wantRules := []Rule{
+		{ID: "match/http", ShortDescription: &Message{Text: "Declaration matching func(http.ResponseWriter, *http.Request)"}, FullDescription: &Message{Text: "HTTP handlers"}},
+		{ID: RuleAmbiguous, ShortDescription: &Message{Text: "More than one declaration matches where one is expected"}},
+	}
+	if !reflect.DeepEqual(run.Tool.Driver.Rules, wantRules) {
+		t.Errorf("Wanted rules %+v, got %+v", wantRules, run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("Wanted 3 results, got %+v", run.Results)
+	}
+	one := Location{PhysicalLocation: PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{URI: "handlers/two.go"},
+		Region:           &Region{StartLine: 5, StartColumn: 6, EndLine: 5, EndColumn: 9},
+	}}
+	if got := run.Results[0]; got.RuleID != "match/http" || got.RuleIndex != 0 || got.Level != LevelNote || !reflect.DeepEqual(got.Locations, []Location{one}) {
+		t.Errorf("Unexpected match %+v", got)
+	}
+	ambiguous := run.Results[2]
+	if ambiguous.RuleID != RuleAmbiguous || ambiguous.RuleIndex != 1 || ambiguous.Level != LevelError {
+		t.Errorf("Unexpected ambiguity %+v", ambiguous)
+	}
+	if len(ambiguous.Locations) != 1 || len(ambiguous.RelatedLocations) != 1 || ambiguous.RelatedLocations[0].PhysicalLocation.Region.StartLine != 7 {
+		t.Errorf("Unexpected ambiguity locations %+v %+v", ambiguous.Locations, ambiguous.RelatedLocations)
+	}
+	if run.Invocations != nil {
+		t.Errorf("Wanted no invocations, got %+v", run.Invocations)
+	}
+}
+
+func TestNearMisses(t *testing.T) {
+	d := detect.NewDetector([]detect.FunctionSignature{httpSignature}, detect.WithExportedOnly())
+	p, err := d.ScanPackage("../detect/testdata/diagnostics")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	r := NewReport("../detect/testdata")
+	r.AddPackage(p)
+	results := r.Log().Runs[0].Results
+	if len(results) != 3 {
+		t.Fatalf("Wanted 3 near misses, got %+v", results)
+	}
+	for _, got := range results {
+		if got.RuleID != "near-miss/http" || got.Level != LevelWarning {
+			t.Errorf("Unexpected result %+v", got)
+		}
+		want := ArtifactLocation{URI: "diagnostics/near.go", URIBaseID: SrcRoot}
+		if got.Locations[0].PhysicalLocation.ArtifactLocation != want {
+			t.Errorf("Wanted %+v, got %+v", want, got.Locations[0].PhysicalLocation.ArtifactLocation)
+		}
+	}
+}
+
+func TestUnsupportedTypes(t *testing.T) {
+	d := detect.NewDetector([]detect.FunctionSignature{httpSignature}, detect.WithExportedOnly())
+	p, err := d.ScanPackage("../detect/testdata/unsupported")
+	if err != nil {
+		t.Fatalf("Failed to scan package: %s", err)
+	}
+	r := NewReport("")
+	r.AddPackage(p)
+	results := r.Log().Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("Wanted a match and an unsupported type, got %+v", results)
+	}
+	got := results[1]
+	want := &Region{StartLine: 10, StartColumn: 14, EndLine: 10, EndColumn: 27}
+	if got.RuleID != RuleUnsupported || got.Level != LevelWarning || !reflect.DeepEqual(got.Locations[0].PhysicalLocation.Region, want) {
+		t.Errorf("Unexpected result %+v", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	d := detect.NewDetector([]detect.FunctionSignature{httpSignature})
+	_, err := d.AllFromFile(&detect.Function{File: "bad.go", Source: "package bad\n\nfunc {"})
+	if err == nil {
+		t.Fatal("Wanted a parse error")
+	}
+	r := NewReport("")
+	r.AddError(err)
+	r.AddError(errors.New("failed to read signatures"))
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("Failed to write log: %s", err)
+	}
+	var log Log
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to read log back: %s", err)
+	}
+	run := log.Runs[0]
+	if len(run.Results) == 0 || run.Results[0].RuleID != RuleParseError || run.Results[0].Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Errorf("Unexpected parse errors %+v", run.Results)
+	}
+	want := []Invocation{{ToolExecutionNotifications: []Notification{{Level: LevelError, Message: Message{Text: "failed to read signatures"}}}}}
+	if !reflect.DeepEqual(run.Invocations, want) {
+		t.Errorf("Wanted %+v, got %+v", want, run.Invocations)
+	}
+}
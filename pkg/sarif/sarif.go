@@ -0,0 +1,106 @@
+// Package sarif writes the results of the detect package as a SARIF 2.1.0
+// log, so that they can be uploaded to code scanning dashboards. Only the
+// parts of the format that are needed for that are modelled here, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package sarif
+
+const (
+	// Version is the version of SARIF that's written.
+	Version = "2.1.0"
+	// Schema is the JSON schema of the written version.
+	Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+	// SrcRoot is the uriBaseId of the artifact locations that are relative
+	// to the base directory of the Report.
+	SrcRoot = "%SRCROOT%"
+)
+
+// Levels of the results.
+const (
+	LevelNote    = "note"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Log is the top level object of a SARIF file.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is a single run of the detector.
+type Run struct {
+	Tool        Tool         `json:"tool"`
+	Invocations []Invocation `json:"invocations,omitempty"`
+	Results     []Result     `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// Rule describes what a result with its ID means.
+type Rule struct {
+	ID               string            `json:"id"`
+	ShortDescription *Message          `json:"shortDescription,omitempty"`
+	FullDescription  *Message          `json:"fullDescription,omitempty"`
+	Properties       map[string]string `json:"properties,omitempty"`
+}
+
+// Invocation tells whether the run succeeded, along with the errors that
+// are not about a location in the source.
+type Invocation struct {
+	ExecutionSuccessful        bool           `json:"executionSuccessful"`
+	ToolExecutionNotifications []Notification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type Notification struct {
+	Level   string  `json:"level"`
+	Message Message `json:"message"`
+}
+
+type Result struct {
+	RuleID    string  `json:"ruleId"`
+	RuleIndex int     `json:"ruleIndex"`
+	Level     string  `json:"level"`
+	Message   Message `json:"message"`
+	// Locations is where the result is, RelatedLocations are the other
+	// places that are part of it, for example the other matches of an
+	// ambiguity.
+	Locations        []Location `json:"locations,omitempty"`
+	RelatedLocations []Location `json:"relatedLocations,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a range in a file. Lines and columns start at 1 and the end
+// column is exclusive.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}